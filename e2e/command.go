@@ -0,0 +1,126 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the commands run by the e2e runner and how they're executed.
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// stdio is how a command is connected to the standard streams of the runner
+type stdio int
+
+// Contains the ways a command can be connected to the standard streams
+const (
+	// stdioNone discards the output of the command
+	stdioNone stdio = iota
+
+	// stdioOut connects the stdout and stderr of the command
+	stdioOut
+
+	// stdioInOut connects the stdin, stdout and stderr of the command
+	stdioInOut
+)
+
+// command is a command run by the e2e runner
+type command struct {
+	// Name is the name, or path, of the program
+	Name string `json:"name"`
+
+	// Args are the arguments of the program
+	Args []string `json:"args,omitempty"`
+
+	// Env are the environment variables, KEY=value, set for the command in
+	// addition to the environment of the runner
+	Env []string `json:"env,omitempty"`
+
+	// stdio is how the command is connected to the standard streams
+	stdio stdio
+}
+
+// newCommand returns a command whose output is discarded
+func newCommand(name string, args ...string) *command {
+	return &command{Name: name, Args: args}
+}
+
+// stdOutErr returns a command connected to the os stdout/err
+func stdOutErr(name string, args ...string) *command {
+	return &command{Name: name, Args: args, stdio: stdioOut}
+}
+
+// stdInOutErr returns a command connected to the os stdin/out/err
+func stdInOutErr(name string, args ...string) *command {
+	return &command{Name: name, Args: args, stdio: stdioInOut}
+}
+
+// withEnv sets environment variables, KEY=value, for the command
+func (c *command) withEnv(env ...string) *command {
+	c.Env = append(c.Env, env...)
+	return c
+}
+
+// String returns the command as it would be typed in a shell
+func (c *command) String() string {
+	parts := make([]string, 0, len(c.Env)+len(c.Args)+1)
+	parts = append(parts, c.Env...)
+	parts = append(parts, c.Name)
+	for _, arg := range c.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'$*?;&|<>()") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// executor executes the commands of the e2e runner
+type executor interface {
+	// Run runs a command and waits for it to finish
+	Run(ctx context.Context, c *command) error
+
+	// Output runs a command and returns its combined stdout and stderr
+	Output(ctx context.Context, c *command) ([]byte, error)
+
+	// Start starts a command without waiting for it to finish
+	Start(ctx context.Context, c *command) error
+}
+
+// osExecutor is the executor that runs commands as processes
+type osExecutor struct{}
+
+// cmd returns the exec.Cmd of a command
+func (osExecutor) cmd(ctx context.Context, c *command) *exec.Cmd {
+	//nolint:gosec // Why: We're OK with this, commands are built by the runner.
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	switch c.stdio {
+	case stdioInOut:
+		cmd.Stdin = os.Stdin
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	case stdioOut:
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	case stdioNone:
+	}
+	return cmd
+}
+
+// Run implements executor
+func (e osExecutor) Run(ctx context.Context, c *command) error {
+	return e.cmd(ctx, c).Run()
+}
+
+// Output implements executor
+func (e osExecutor) Output(ctx context.Context, c *command) ([]byte, error) {
+	return e.cmd(ctx, c).CombinedOutput()
+}
+
+// Start implements executor
+func (e osExecutor) Start(ctx context.Context, c *command) error {
+	return e.cmd(ctx, c).Start()
+}
@@ -0,0 +1,37 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the alias table of service names.
+
+package config
+
+// Aliases maps old names of services, e.g. of renamed repositories, to
+// their current name
+type Aliases map[string]string
+
+// Resolve returns the current name of a service, following aliases of
+// aliases, and whether an alias was used. Cyclical aliases resolve to the
+// last name before the cycle.
+func (a Aliases) Resolve(name string) (string, bool) {
+	seen := map[string]bool{name: true}
+	cur := name
+	for {
+		next, ok := a[cur]
+		if !ok || next == "" || seen[next] {
+			return cur, cur != name
+		}
+		seen[next] = true
+		cur = next
+	}
+}
+
+// Merge returns the aliases of a overlaid with those of b
+func (a Aliases) Merge(b Aliases) Aliases {
+	merged := make(Aliases, len(a)+len(b))
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range b {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAliasesResolve(t *testing.T) {
+	a := Aliases{"old": "newer", "newer": "newest", "x": "y", "y": "x"}
+
+	tests := []struct {
+		name    string
+		current string
+		aliased bool
+	}{
+		{name: "old", current: "newest", aliased: true},
+		{name: "newer", current: "newest", aliased: true},
+		{name: "newest", current: "newest"},
+		{name: "x", current: "y", aliased: true},
+	}
+
+	for _, tt := range tests {
+		current, aliased := a.Resolve(tt.name)
+		assert.Equal(t, tt.current, current, tt.name)
+		assert.Equal(t, tt.aliased, aliased, tt.name)
+	}
+}
+
+func TestAliasesMerge(t *testing.T) {
+	a := Aliases{"a": "b", "c": "d"}
+	assert.Equal(t, Aliases{"a": "e", "c": "d", "f": "g"}, a.Merge(Aliases{"a": "e", "f": "g"}))
+	assert.Equal(t, Aliases{"a": "b", "c": "d"}, a)
+}
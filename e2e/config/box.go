@@ -0,0 +1,80 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the devbase specific configuration in box.
+
+package config
+
+import (
+	"strings"
+
+	"github.com/getoutreach/gobox/pkg/box"
+	"github.com/pkg/errors"
+)
+
+// DefaultReadinessNamespace is the namespace the deployments of a
+// dependency are waited for in, unless configured otherwise, {name} being
+// the name of the dependency. It's the namespace devenv deploys services
+// to.
+const DefaultReadinessNamespace = "{name}--bento1a"
+
+// BoxDevbase is the devbase specific configuration of an organisation,
+// read from the devbase key of the box configuration:
+//
+//	config:
+//	  devbase:
+//	    provisionTargets:
+//	      rules:
+//	        - dependency: outreach
+//	          target: flagship
+//	    aliases:
+//	      flagship: outreach
+//	    readiness:
+//	      namespace: "{name}"
+type BoxDevbase struct {
+	// ProvisionTargets configures which devenv provision target is used
+	// based on the dependencies of the repository being tested
+	ProvisionTargets ProvisionTargets `yaml:"provisionTargets,omitempty"`
+
+	// Aliases maps old names of services to their current name
+	Aliases Aliases `yaml:"aliases,omitempty"`
+
+	// Readiness configures how the readiness of dependencies is waited
+	// for when deploying in waves
+	Readiness BoxReadiness `yaml:"readiness,omitempty"`
+}
+
+// BoxReadiness configures how the readiness of dependencies is waited for
+type BoxReadiness struct {
+	// Namespace is the Kubernetes namespace of the deployments of a
+	// dependency, {name} being replaced by the name of the dependency.
+	// Defaults to DefaultReadinessNamespace.
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// NamespaceOf returns the namespace of the deployments of the named
+// dependency
+func (r *BoxReadiness) NamespaceOf(name string) string {
+	ns := r.Namespace
+	if ns == "" {
+		ns = DefaultReadinessNamespace
+	}
+	return strings.ReplaceAll(ns, "{name}", name)
+}
+
+// LoadBoxDevbase reads the devbase specific configuration from the box
+// configuration on disk. The box configuration is expected to have been
+// loaded with box.EnsureBoxWithOptions before.
+func LoadBoxDevbase() (*BoxDevbase, error) {
+	s, _, err := box.LoadBoxStorage()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load box config")
+	}
+
+	var conf struct {
+		Devbase BoxDevbase `yaml:"devbase"`
+	}
+	if err := s.Config.Decode(&conf); err != nil {
+		return nil, errors.Wrap(err, "failed to parse devbase box config")
+	}
+	return &conf.Devbase, nil
+}
@@ -7,90 +7,361 @@ package config
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/getoutreach/devbase/v2/pkg/forge"
+	"github.com/getoutreach/devbase/v2/pkg/manifest"
 	"github.com/getoutreach/gobox/pkg/box"
 	"github.com/google/go-github/v58/github"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 // Devenv is a struct that contains the devenv configuration
 // which is usually called "devenv.yaml". This also works for the
 // legacy service.yaml format.
 type Devenv struct {
+	// APIVersion is the version of the schema of the file, empty or "v1"
+	// for the legacy format and "v2" for DevenvV2
+	APIVersion string `yaml:"apiVersion,omitempty"`
+
 	// Service denotes if this repository is a service.
 	Service bool `yaml:"service"`
 
-	Dependencies struct {
-		// Optional is a list of OPTIONAL services e.g. the service can run / gracefully function without it running
-		Optional []string `yaml:"optional"`
+	// Dependencies are the services this repository depends on
+	Dependencies Dependencies `yaml:"dependencies"`
+
+	// Resolution configures how the transitive dependencies of this
+	// repository are resolved. Only used for the repository being tested.
+	Resolution Resolution `yaml:"resolution"`
+
+	// ProvisionTargets configures which devenv provision target is used
+	// based on the dependencies. Only used for the repository being tested.
+	ProvisionTargets ProvisionTargets `yaml:"provisionTargets,omitempty"`
 
-		// Required is a list of services that this service cannot function without
-		Required []string `yaml:"required"`
-	} `yaml:"dependencies"`
+	// Aliases maps old names of services to their current name. Only used
+	// for the repository being tested.
+	Aliases Aliases `yaml:"aliases,omitempty"`
+
+	// Profiles are named sets of dependencies that can be used instead of
+	// Dependencies, see WithProfile. Only used for the repository being
+	// tested.
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
 }
 
-// FromFile parses the devenv.yaml file and returns a DevenvConfig
-func FromFile(confPath string) (*Devenv, error) {
-	f, err := os.Open(confPath)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to read devenv.yaml or service.yaml")
+// Dependencies are the services a repository depends on
+type Dependencies struct {
+	// Optional is a list of OPTIONAL services e.g. the service can run / gracefully function without it running
+	Optional []Dependency `yaml:"optional"`
+
+	// Required is a list of services that this service cannot function without
+	Required []Dependency `yaml:"required"`
+}
+
+// markOptional sets Optional of every dependency according to the list
+// it's in
+func (d *Dependencies) markOptional() {
+	for i := range d.Required {
+		d.Required[i].Optional = false
 	}
-	defer f.Close()
+	for i := range d.Optional {
+		d.Optional[i].Optional = true
+	}
+}
 
-	var dc Devenv
-	if err := yaml.NewDecoder(f).Decode(&dc); err != nil {
-		return nil, errors.Wrapf(err, "failed to parse devenv.yaml or service.yaml")
+// Pinned returns whether any of the dependencies is pinned to a ref
+func (d *Dependencies) Pinned() bool {
+	for _, dep := range append(append([]Dependency{}, d.Required...), d.Optional...) {
+		if dep.Ref != "" {
+			return true
+		}
 	}
+	return false
+}
+
+// OptionalPolicy determines which optional dependencies are included
+// when resolving transitive dependencies
+type OptionalPolicy string
+
+// Contains the possible optional dependency policies
+const (
+	// OptionalNone includes no optional dependencies at all
+	OptionalNone OptionalPolicy = "none"
 
-	return &dc, nil
+	// OptionalRoot includes only the optional dependencies of the
+	// repository being tested, not those of its dependencies
+	OptionalRoot OptionalPolicy = "root"
+
+	// OptionalAll includes the optional dependencies of every dependency
+	OptionalAll OptionalPolicy = "all"
+)
+
+// Validate returns an error if the policy is not a known policy, an
+// empty policy is valid and means the default, OptionalRoot.
+func (p OptionalPolicy) Validate() error {
+	switch p {
+	case "", OptionalNone, OptionalRoot, OptionalAll:
+		return nil
+	default:
+		return fmt.Errorf("unknown optional dependency policy %q, expected one of [none root all]", p)
+	}
 }
 
-// ReadServiceName reads service name from service.yaml
-func ReadServiceName() (string, error) {
-	configFileName := "service.yaml"
-	b, err := os.ReadFile(configFileName)
+// Resolution configures how transitive dependencies are resolved
+type Resolution struct {
+	// OptionalDependencies is which optional dependencies are included,
+	// one of "none", "root" or "all". Defaults to "root".
+	OptionalDependencies OptionalPolicy `yaml:"optionalDependencies,omitempty"`
+
+	// MaxDepth is the maximum depth of the dependency tree, where the
+	// dependencies of this repository are at depth 1. Dependencies deeper
+	// than this are not included. Defaults to 0, no limit.
+	MaxDepth int `yaml:"maxDepth,omitempty"`
+}
+
+// Dependency is a dependency on another service, optionally pinned to a
+// git ref (branch, tag or commit) of its repository. It's written as either
+// a string, "name" or "name@ref", or as a mapping with name and ref keys.
+type Dependency struct {
+	// Name is the name of the service (repository)
+	Name string `yaml:"name"`
+
+	// Ref is the git ref the service is pinned to. When empty, the default
+	// branch (or for deploys, the latest version) of the service is used.
+	Ref string `yaml:"ref,omitempty"`
+
+	// Optional denotes if this is an optional dependency. Only used in
+	// DevenvV2, in the legacy format the list a dependency is in decides.
+	Optional bool `yaml:"optional,omitempty"`
+
+	// ProvisionTarget is the devenv provision target (snapshot) this
+	// dependency needs, e.g. flagship
+	ProvisionTarget string `yaml:"provisionTarget,omitempty"`
+
+	// Readiness configures how to check if this dependency is ready
+	// after it has been deployed
+	Readiness *Readiness `yaml:"readiness,omitempty"`
+}
+
+// Readiness configures how to check if a dependency is ready
+type Readiness struct {
+	// Deployments are the names of the Kubernetes deployments that need to
+	// be available. When empty, all deployments of the service need to be.
+	Deployments []string `yaml:"deployments,omitempty" json:"deployments,omitempty"`
+
+	// Timeout is how long the dependency may take to become ready, e.g. 5m
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Namespace is the Kubernetes namespace of the deployments, overriding
+	// the one of the box configuration, see BoxReadiness
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+}
+
+// ValidateRef returns an error if ref can't be a git ref (branch, tag or
+// commit) a dependency is pinned to, following the rules of
+// `git check-ref-format`. Refs that git could mistake for an option, or
+// that could escape a directory they're part of the path of, are
+// rejected as they're read from the devenv.yaml of dependencies. An empty
+// ref is valid.
+func ValidateRef(ref string) error {
+	switch {
+	case ref == "":
+		return nil
+	case strings.HasPrefix(ref, "-"):
+		return fmt.Errorf("ref %q must not start with a dash", ref)
+	case strings.HasPrefix(ref, "/"), strings.HasSuffix(ref, "/"), strings.HasSuffix(ref, "."),
+		strings.HasSuffix(ref, ".lock"), ref == "@":
+		return fmt.Errorf("ref %q is not a valid git ref", ref)
+	}
+
+	for _, s := range []string{"..", "//", "@{", "/."} {
+		if strings.Contains(ref, s) {
+			return fmt.Errorf("ref %q must not contain %q", ref, s)
+		}
+	}
+	for _, r := range ref {
+		if r <= ' ' || r == 0x7f || strings.ContainsRune(":~^?*[\\", r) {
+			return fmt.Errorf("ref %q must not contain %q", ref, r)
+		}
+	}
+	return nil
+}
+
+// ValidateName returns an error if name can't be the name of a
+// dependency, which is a repository name. Names are joined into paths of
+// checkouts and caches, so "." and ".." are rejected as they're read from
+// the devenv.yaml of dependencies.
+func ValidateName(name string) error {
+	if !repoNameRe.MatchString(name) || name == "." || name == ".." {
+		return fmt.Errorf("dependency %q is not a valid repository name", name)
+	}
+	return nil
+}
+
+// ParseDependency parses a dependency in the "name" or "name@ref" form,
+// returning an error if the name or ref is invalid, see ValidateName and
+// ValidateRef
+func ParseDependency(s string) (Dependency, error) {
+	name, ref, _ := strings.Cut(s, "@")
+	d := Dependency{Name: name, Ref: ref}
+	return d, d.validate()
+}
+
+// validate returns an error if the name or ref of the dependency is invalid
+func (d Dependency) validate() error {
+	if err := ValidateName(d.Name); err != nil {
+		return err
+	}
+	return errors.Wrapf(ValidateRef(d.Ref), "dependency %s", d.Name)
+}
+
+// String returns the dependency in the "name" or "name@ref" form
+func (d Dependency) String() string {
+	if d.Ref == "" {
+		return d.Name
+	}
+	return d.Name + "@" + d.Ref
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, accepting both the string
+// and the mapping form of a dependency. Dependencies with an invalid name
+// or pinned to an invalid ref, see ValidateName and ValidateRef, are
+// rejected.
+func (d *Dependency) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*d, err = ParseDependency(s)
+		return invalidDependency(err)
+	}
+
+	// Use a type without UnmarshalYAML to decode the mapping form
+	type dependency Dependency
+	var dep dependency
+	if err := unmarshal(&dep); err != nil {
+		return err
+	}
+	*d = Dependency(dep)
+	return invalidDependency(d.validate())
+}
+
+// invalidDependency returns err as a yaml.v3 TypeError, which doesn't
+// stop Validate from decoding the rest of the file
+func invalidDependency(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &yamlv3.TypeError{Errors: []string{err.Error()}}
+}
+
+// MarshalYAML implements yaml.Marshaler, using the string form unless
+// the dependency has metadata other than its ref
+func (d Dependency) MarshalYAML() (interface{}, error) {
+	if !d.Optional && d.ProvisionTarget == "" && d.Readiness == nil {
+		return d.String(), nil
+	}
+
+	type dependency Dependency
+	return dependency(d), nil
+}
+
+// FromFile parses the devenv.yaml file and returns a DevenvConfig
+func FromFile(confPath string) (*Devenv, error) {
+	b, err := os.ReadFile(confPath)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to read %s", configFileName)
+		return nil, errors.Wrap(err, "failed to read devenv.yaml or service.yaml")
 	}
 
-	// conf is a partial of the configuration file for services configured with
-	// bootstrap (stencil).
-	var conf struct {
-		Name string `yaml:"name"`
+	return Parse(b)
+}
+
+// Parse parses the contents of a devenv.yaml, or legacy service.yaml,
+// file. Both the legacy format and DevenvV2 are accepted.
+func Parse(b []byte) (*Devenv, error) {
+	var v struct {
+		APIVersion string `yaml:"apiVersion"`
 	}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, errors.Wrap(err, "failed to parse devenv.yaml or service.yaml")
+	}
+
+	switch v.APIVersion {
+	case "", APIVersionV1:
+		var dc Devenv
+		if err := yaml.Unmarshal(b, &dc); err != nil {
+			return nil, errors.Wrap(err, "failed to parse devenv.yaml or service.yaml")
+		}
 
-	if err := yaml.Unmarshal(b, &conf); err != nil {
-		return "", errors.Wrapf(err, "failed to parse %s", configFileName)
+		// The list a dependency is in decides if it's optional
+		dc.Dependencies.markOptional()
+		for name, p := range dc.Profiles {
+			p.Dependencies.markOptional()
+			dc.Profiles[name] = p
+		}
+		return &dc, nil
+	case APIVersionV2:
+		var dc DevenvV2
+		if err := yaml.Unmarshal(b, &dc); err != nil {
+			return nil, errors.Wrap(err, "failed to parse devenv.yaml")
+		}
+		return dc.Devenv(), nil
+	default:
+		return nil, fmt.Errorf("unknown devenv.yaml apiVersion %q, expected one of [v1 v2]", v.APIVersion)
 	}
+}
 
-	return conf.Name, nil
+// ReadServiceName reads service name from the service.yaml of the
+// repository the current working directory is in
+//
+// Deprecated: Use manifest.Load instead.
+func ReadServiceName() (string, error) {
+	s, err := manifest.Load(".")
+	if err != nil {
+		return "", err
+	}
+	return s.Name, nil
 }
 
 // FromGitHub reads and parses DevenvConfig from GitHub
 func FromGitHub(ctx context.Context, conf *box.Config, serviceName string,
 	gh *github.Client, configFileName string) (*Devenv, error) {
-	r, _, err := gh.Repositories.DownloadContents(ctx, conf.Org, serviceName, configFileName, nil)
-	l := log.With().Str("service", serviceName).Str("file", configFileName).Logger()
+	p, err := forge.NewGitHub(gh)
+	if err != nil {
+		return nil, err
+	}
+	return FromForge(ctx, p, conf.Org+"/"+serviceName, configFileName, "")
+}
+
+// FromForge reads and parses DevenvConfig from a repository, e.g.
+// "getoutreach/devbase", on a forge at the given ref, or the default branch
+// when empty
+func FromForge(ctx context.Context, p forge.Provider, repo, configFileName, ref string) (*Devenv, error) {
+	l := log.With().Str("repo", repo).Str("file", configFileName).Str("forge", p.Name()).Logger()
+	b, err := p.ReadFile(ctx, repo, configFileName, ref)
 	if err != nil {
-		l.Debug().Msg("Unable to find file in GH")
+		l.Debug().Msg("Unable to find file in forge")
 		return nil, err
 	}
-	defer r.Close()
-	var dc Devenv
-	if err := yaml.NewDecoder(r).Decode(&dc); err != nil {
+
+	dc, err := Parse(b)
+	if err != nil {
 		l.Warn().Msg("Unable to parse config file")
 		return nil, err
 	}
-	return &dc, nil
+	return dc, nil
 }
 
-// getDependencies returns all dependencies
+// GetAllDependencies returns the names of all dependencies
 func (c *Devenv) GetAllDependencies() []string {
 	deps := make([]string, 0)
-	deps = append(deps, c.Dependencies.Required...)
-	deps = append(deps, c.Dependencies.Optional...)
+	for _, d := range c.Dependencies.Required {
+		deps = append(deps, d.Name)
+	}
+	for _, d := range c.Dependencies.Optional {
+		deps = append(deps, d.Name)
+	}
 	return deps
 }
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDependency(t *testing.T) {
+	d, err := ParseDependency("a@v1")
+	assert.NoError(t, err)
+	assert.Equal(t, Dependency{Name: "a", Ref: "v1"}, d)
+
+	for _, s := range []string{"", ".", "..", "../../..", "a/b", "..@main", "a@--upload-pack=x"} {
+		_, err := ParseDependency(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseTraversalNames(t *testing.T) {
+	for _, conf := range []string{
+		"dependencies:\n  required:\n    - ../../..\n",
+		"apiVersion: v2\ndependencies:\n  - name: ../../..\n    ref: main\n",
+		"apiVersion: v2\ndependencies:\n  - name: ..\n",
+	} {
+		_, err := Parse([]byte(conf))
+		assert.ErrorContains(t, err, "is not a valid repository name", conf)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains dependency profiles of devenv.yaml.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the name of the implicit profile containing the
+// top-level dependencies, which profiles can extend
+const DefaultProfile = "default"
+
+// Profile is a named set of dependencies, e.g. a minimal set to smoke
+// test one flow
+type Profile struct {
+	// Extends are the names of the profiles whose dependencies are
+	// included in this profile, DefaultProfile being the top-level
+	// dependencies
+	Extends []string `yaml:"extends,omitempty"`
+
+	// Dependencies are the dependencies of this profile, in addition to
+	// those of the profiles it extends
+	Dependencies Dependencies `yaml:"dependencies,omitempty"`
+}
+
+// WithProfile returns a copy of the configuration whose dependencies are
+// those of the named profile, including those of the profiles it
+// extends. A dependency that is both required and optional is required.
+// An empty name, or DefaultProfile, returns the configuration as-is.
+func (c *Devenv) WithProfile(name string) (*Devenv, error) {
+	if name == "" || name == DefaultProfile {
+		return c, nil
+	}
+
+	deps, err := c.profileDependencies(name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dc := *c
+	dc.Dependencies = Dependencies{}
+	required := make(map[string]bool)
+	for _, d := range deps.Required {
+		if !required[d.Name] {
+			required[d.Name] = true
+			dc.Dependencies.Required = append(dc.Dependencies.Required, d)
+		}
+	}
+	optional := make(map[string]bool)
+	for _, d := range deps.Optional {
+		if !required[d.Name] && !optional[d.Name] {
+			optional[d.Name] = true
+			dc.Dependencies.Optional = append(dc.Dependencies.Optional, d)
+		}
+	}
+	return &dc, nil
+}
+
+// profileDependencies returns the dependencies of a profile, including
+// those of the profiles it extends, with duplicates. stack is the chain of
+// profiles being extended, used to detect cycles.
+func (c *Devenv) profileDependencies(name string, stack []string) (Dependencies, error) {
+	if name == DefaultProfile {
+		return c.Dependencies, nil
+	}
+	for _, s := range stack {
+		if s == name {
+			return Dependencies{}, fmt.Errorf("profiles extend each other: %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Dependencies{}, fmt.Errorf("unknown profile %q, expected one of %v", name, c.ProfileNames())
+	}
+
+	var deps Dependencies
+	for _, parent := range p.Extends {
+		pd, err := c.profileDependencies(parent, append(stack, name))
+		if err != nil {
+			return Dependencies{}, err
+		}
+		deps.Required = append(deps.Required, pd.Required...)
+		deps.Optional = append(deps.Optional, pd.Optional...)
+	}
+	deps.Required = append(deps.Required, p.Dependencies.Required...)
+	deps.Optional = append(deps.Optional, p.Dependencies.Optional...)
+	return deps, nil
+}
+
+// ProfileNames returns the names of all profiles, including
+// DefaultProfile, sorted
+func (c *Devenv) ProfileNames() []string {
+	names := []string{DefaultProfile}
+	for name := range c.Profiles {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithProfile(t *testing.T) {
+	dc, err := Parse([]byte(`dependencies:
+  required: [a, b]
+  optional: [c]
+profiles:
+  minimal:
+    dependencies:
+      required: [a]
+  smoke:
+    extends: [minimal]
+    dependencies:
+      required: [d]
+      optional: [a, e]
+  full:
+    extends: [default, smoke]
+  loop:
+    extends: [loop2]
+  loop2:
+    extends: [loop]
+`))
+	assert.NoError(t, err)
+
+	names := func(deps []Dependency) []string {
+		n := make([]string, 0)
+		for _, d := range deps {
+			n = append(n, d.Name)
+		}
+		return n
+	}
+
+	tests := []struct {
+		profile  string
+		required []string
+		optional []string
+		err      string
+	}{
+		{profile: "", required: []string{"a", "b"}, optional: []string{"c"}},
+		{profile: "minimal", required: []string{"a"}, optional: []string{}},
+		// a is required by minimal, so it stays required
+		{profile: "smoke", required: []string{"a", "d"}, optional: []string{"e"}},
+		{profile: "full", required: []string{"a", "b", "d"}, optional: []string{"c", "e"}},
+		{profile: "loop", err: "profiles extend each other: loop -> loop2 -> loop"},
+		{profile: "missing", err: `unknown profile "missing", expected one of [default full loop loop2 minimal smoke]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			p, err := dc.WithProfile(tt.profile)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.required, names(p.Dependencies.Required))
+			assert.Equal(t, tt.optional, names(p.Dependencies.Optional))
+		})
+	}
+}
+
+func TestWithProfileV2(t *testing.T) {
+	dc, err := Parse([]byte(`apiVersion: v2
+dependencies:
+  - a
+profiles:
+  minimal:
+    dependencies:
+      - b
+      - name: c
+        optional: true
+`))
+	assert.NoError(t, err)
+
+	p, err := dc.WithProfile("minimal")
+	assert.NoError(t, err)
+	assert.Equal(t, Dependencies{
+		Required: []Dependency{{Name: "b"}},
+		Optional: []Dependency{{Name: "c", Optional: true}},
+	}, p.Dependencies)
+}
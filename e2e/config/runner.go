@@ -0,0 +1,84 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the settings of the e2e runner read from the environment.
+
+package config
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultReadyTimeout is how long a wave of dependencies may take to
+// become ready when deploying in waves
+const DefaultReadyTimeout = 10 * time.Minute
+
+// Runner are the settings of the e2e runner that are read from the
+// environment, see RunnerFromEnv. The settings of resolving dependencies
+// are deps.Options.
+type Runner struct {
+	// SkipProvision uses the current devenv, without provisioning one
+	SkipProvision bool
+
+	// SkipLocalizer doesn't open a tunnel to the devenv before testing
+	SkipLocalizer bool
+
+	// DevconfigAfterDeploy runs devconfig after deploying the application,
+	// instead of in the background
+	DevconfigAfterDeploy bool
+
+	// UseDevspace runs the tests in the devenv with devspace
+	UseDevspace bool
+
+	// DeployWaves deploys the dependencies in topologically ordered waves
+	DeployWaves bool
+
+	// Teardown is when the devenv is destroyed once the run is done, the
+	// default depends on the run
+	Teardown string
+
+	// ReadyTimeout is how long a wave of dependencies may take to become
+	// ready when deploying in waves
+	ReadyTimeout time.Duration
+}
+
+// EnvBool returns whether the environment variable key is "true", the
+// only value that is true, like the Makefile and the shell scripts
+// compare it, e.g. SKIP_LOCALIZER=1 is false
+func EnvBool(key string) bool {
+	return os.Getenv(key) == "true"
+}
+
+// RunnerFromEnv returns the settings of the e2e runner configured through
+// the environment:
+//
+//   - SKIP_DEVENV_PROVISION: SkipProvision
+//   - SKIP_LOCALIZER: SkipLocalizer
+//   - REQUIRE_DEVCONFIG_AFTER_DEPLOY: DevconfigAfterDeploy
+//   - USE_DEVSPACE: UseDevspace
+//   - E2E_DEPLOY_WAVES: DeployWaves
+//   - E2E_TEARDOWN: Teardown
+//   - E2E_READY_TIMEOUT: ReadyTimeout as a duration, e.g. 5m (default: DefaultReadyTimeout)
+//
+// Bools are only true when they're exactly "true", see EnvBool.
+func RunnerFromEnv() (*Runner, error) {
+	r := &Runner{
+		SkipProvision:        EnvBool("SKIP_DEVENV_PROVISION"),
+		SkipLocalizer:        EnvBool("SKIP_LOCALIZER"),
+		DevconfigAfterDeploy: EnvBool("REQUIRE_DEVCONFIG_AFTER_DEPLOY"),
+		UseDevspace:          EnvBool("USE_DEVSPACE"),
+		DeployWaves:          EnvBool("E2E_DEPLOY_WAVES"),
+		Teardown:             os.Getenv("E2E_TEARDOWN"),
+		ReadyTimeout:         DefaultReadyTimeout,
+	}
+
+	if v := os.Getenv("E2E_READY_TIMEOUT"); v != "" {
+		var err error
+		if r.ReadyTimeout, err = time.ParseDuration(v); err != nil {
+			return nil, errors.Wrapf(err, "invalid E2E_READY_TIMEOUT %q", v)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerFromEnv(t *testing.T) {
+	t.Setenv("SKIP_DEVENV_PROVISION", "1")
+	t.Setenv("SKIP_LOCALIZER", "true")
+	t.Setenv("E2E_READY_TIMEOUT", "")
+	r, err := RunnerFromEnv()
+	assert.NoError(t, err)
+	assert.False(t, r.SkipProvision, "only true is true")
+	assert.True(t, r.SkipLocalizer)
+	assert.Equal(t, DefaultReadyTimeout, r.ReadyTimeout)
+
+	t.Setenv("E2E_READY_TIMEOUT", "5m")
+	r, err = RunnerFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, r.ReadyTimeout)
+
+	t.Setenv("E2E_READY_TIMEOUT", "soon")
+	_, err = RunnerFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,31 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the configuration of devenv provision targets.
+
+package config
+
+// ProvisionTargets configures which devenv provision target (snapshot)
+// is used based on the dependencies of the repository being tested
+type ProvisionTargets struct {
+	// Default is the target used when no rule matches
+	Default string `yaml:"default,omitempty"`
+
+	// Rules select a target when a dependency is present, the matching
+	// rule with the highest priority wins
+	Rules []ProvisionTargetRule `yaml:"rules,omitempty"`
+}
+
+// ProvisionTargetRule selects a provision target when a dependency is
+// present in the dependency tree
+type ProvisionTargetRule struct {
+	// Dependency is the name of the service that needs to be present
+	Dependency string `yaml:"dependency" json:"dependency"`
+
+	// Target is the provision target to use
+	Target string `yaml:"target" json:"target"`
+
+	// Priority orders matching rules, higher wins. Rules with the same
+	// priority are ordered by where they're configured: devenv.yaml, then
+	// box, then dependency metadata.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the v2 schema of devenv.yaml.
+
+package config
+
+// Contains the versions of the devenv.yaml schema
+const (
+	// APIVersionV1 is the legacy format, also used when there is no
+	// apiVersion, with separate lists of required and optional
+	// dependencies
+	APIVersionV1 = "v1"
+
+	// APIVersionV2 is the format of DevenvV2
+	APIVersionV2 = "v2"
+)
+
+// DevenvV2 is the v2 schema of devenv.yaml, in which dependencies are a
+// single list and every dependency can carry metadata:
+//
+//	apiVersion: v2
+//	service: true
+//	dependencies:
+//	  - outreach-accounts
+//	  - name: outreach
+//	    ref: v1.2.3
+//	    provisionTarget: flagship
+//	    readiness:
+//	      deployments: [outreach-web]
+//	      timeout: 15m
+//	  - name: mint
+//	    optional: true
+type DevenvV2 struct {
+	// APIVersion is the version of the schema, always "v2"
+	APIVersion string `yaml:"apiVersion"`
+
+	// Service denotes if this repository is a service.
+	Service bool `yaml:"service"`
+
+	// Dependencies are the services this repository depends on, which
+	// are required unless marked as optional
+	Dependencies []Dependency `yaml:"dependencies,omitempty"`
+
+	// Resolution configures how the transitive dependencies of this
+	// repository are resolved. Only used for the repository being tested.
+	Resolution Resolution `yaml:"resolution,omitempty"`
+
+	// ProvisionTargets configures which devenv provision target is used
+	// based on the dependencies. Only used for the repository being tested.
+	ProvisionTargets ProvisionTargets `yaml:"provisionTargets,omitempty"`
+
+	// Aliases maps old names of services to their current name. Only used
+	// for the repository being tested.
+	Aliases Aliases `yaml:"aliases,omitempty"`
+
+	// Profiles are named sets of dependencies that can be used instead of
+	// Dependencies. Only used for the repository being tested.
+	Profiles map[string]ProfileV2 `yaml:"profiles,omitempty"`
+}
+
+// ProfileV2 is a Profile in the v2 schema
+type ProfileV2 struct {
+	// Extends are the names of the profiles whose dependencies are
+	// included in this profile, "default" being the top-level dependencies
+	Extends []string `yaml:"extends,omitempty"`
+
+	// Dependencies are the dependencies of this profile, in addition to
+	// those of the profiles it extends
+	Dependencies []Dependency `yaml:"dependencies,omitempty"`
+}
+
+// Devenv converts the configuration into the format used by the rest of
+// devbase
+func (c *DevenvV2) Devenv() *Devenv {
+	dc := &Devenv{
+		APIVersion:       APIVersionV2,
+		Service:          c.Service,
+		Dependencies:     splitDependencies(c.Dependencies),
+		Resolution:       c.Resolution,
+		ProvisionTargets: c.ProvisionTargets,
+		Aliases:          c.Aliases,
+	}
+	if len(c.Profiles) > 0 {
+		dc.Profiles = make(map[string]Profile, len(c.Profiles))
+		for name, p := range c.Profiles {
+			dc.Profiles[name] = Profile{Extends: p.Extends, Dependencies: splitDependencies(p.Dependencies)}
+		}
+	}
+	return dc
+}
+
+// V2 converts the configuration into the v2 schema, required dependencies
+// are listed before optional ones
+func (c *Devenv) V2() *DevenvV2 {
+	dc := &DevenvV2{
+		APIVersion:       APIVersionV2,
+		Service:          c.Service,
+		Dependencies:     joinDependencies(c.Dependencies),
+		Resolution:       c.Resolution,
+		ProvisionTargets: c.ProvisionTargets,
+		Aliases:          c.Aliases,
+	}
+	if len(c.Profiles) > 0 {
+		dc.Profiles = make(map[string]ProfileV2, len(c.Profiles))
+		for name, p := range c.Profiles {
+			dc.Profiles[name] = ProfileV2{Extends: p.Extends, Dependencies: joinDependencies(p.Dependencies)}
+		}
+	}
+	return dc
+}
+
+// splitDependencies splits a v2 list of dependencies into required and
+// optional dependencies
+func splitDependencies(deps []Dependency) Dependencies {
+	var split Dependencies
+	for _, d := range deps {
+		if d.Optional {
+			split.Optional = append(split.Optional, d)
+		} else {
+			split.Required = append(split.Required, d)
+		}
+	}
+	return split
+}
+
+// joinDependencies joins required and optional dependencies into a v2
+// list of dependencies
+func joinDependencies(deps Dependencies) []Dependency {
+	joined := make([]Dependency, 0, len(deps.Required)+len(deps.Optional))
+	for _, d := range deps.Required {
+		d.Optional = false
+		joined = append(joined, d)
+	}
+	for _, d := range deps.Optional {
+		d.Optional = true
+		joined = append(joined, d)
+	}
+	if len(joined) == 0 {
+		return nil
+	}
+	return joined
+}
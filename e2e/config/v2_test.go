@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseV2(t *testing.T) {
+	dc, err := Parse([]byte(`apiVersion: v2
+service: true
+dependencies:
+  - a@v1
+  - name: b
+    optional: true
+    provisionTarget: flagship
+    readiness:
+      deployments: [b-web]
+      timeout: 5m
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, &Devenv{
+		APIVersion: APIVersionV2,
+		Service:    true,
+		Dependencies: Dependencies{
+			Required: []Dependency{{Name: "a", Ref: "v1"}},
+			Optional: []Dependency{{Name: "b", Optional: true, ProvisionTarget: "flagship",
+				Readiness: &Readiness{Deployments: []string{"b-web"}, Timeout: "5m"}}},
+		},
+	}, dc)
+
+	_, err = Parse([]byte("apiVersion: v3\n"))
+	assert.Error(t, err)
+}
+
+func TestDevenvV2(t *testing.T) {
+	dc, err := Parse([]byte("service: true\ndependencies:\n  required:\n    - a@v1\n  optional:\n    - b\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Dependency{{Name: "b", Optional: true}}, dc.Dependencies.Optional)
+
+	b, err := yaml.Marshal(dc.V2())
+	assert.NoError(t, err)
+	assert.Equal(t, "apiVersion: v2\nservice: true\ndependencies:\n- a@v1\n- name: b\n  optional: true\n", string(b))
+
+	// Converting back and forth is lossless
+	v2, err := Parse(b)
+	assert.NoError(t, err)
+	assert.Equal(t, dc.Dependencies, v2.Dependencies)
+}
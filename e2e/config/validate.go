@@ -0,0 +1,441 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains strict validation of devenv.yaml and service.yaml.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getoutreach/devbase/v2/pkg/manifest"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// repoNameRe matches valid repository names
+var repoNameRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+
+// typeErrorRe matches an error of a yaml.v3 TypeError
+var typeErrorRe = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// unknownFieldRe matches the error of a yaml.v3 TypeError for an unknown
+// key
+var unknownFieldRe = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+
+// wrongTypeRe matches the error of a yaml.v3 TypeError for a value of the
+// wrong type
+var wrongTypeRe = regexp.MustCompile("^cannot unmarshal (!!\\w+)(?: `.*`)? into \\S+$")
+
+// yamlKinds maps yaml tags to how values of those tags are called
+var yamlKinds = map[string]string{
+	"!!seq":   "a list",
+	"!!map":   "a mapping",
+	"!!str":   "a string",
+	"!!bool":  "a boolean",
+	"!!int":   "a number",
+	"!!float": "a number",
+	"!!null":  "empty",
+}
+
+// ValidationError is a problem with a configuration file, at a position
+// in that file
+type ValidationError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+
+	// Warning denotes a problem that doesn't prevent using the file, e.g.
+	// an unknown key of service.yaml outside of the devenv keys
+	Warning bool
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	msg := e.Msg
+	if e.Warning {
+		msg = "warning: " + msg
+	}
+
+	switch {
+	case e.Line == 0:
+		return fmt.Sprintf("%s: %s", e.File, msg)
+	case e.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, msg)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, msg)
+	}
+}
+
+// ValidationErrors are all problems found in a configuration file
+type ValidationErrors []*ValidationError
+
+// Error implements error
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Split splits the problems into warnings and the others, which are
+// errors
+func (errs ValidationErrors) Split() (warnings, severe ValidationErrors) {
+	for _, err := range errs {
+		if err.Warning {
+			warnings = append(warnings, err)
+		} else {
+			severe = append(severe, err)
+		}
+	}
+	return warnings, severe
+}
+
+// ServiceManifest is service.yaml, the configuration of the service for
+// stencil, which also contains the legacy format of devenv.yaml
+type ServiceManifest struct {
+	Devenv           `yaml:",inline"`
+	manifest.Service `yaml:",inline"`
+
+	// DirReplacements maps paths of templates to the paths they're
+	// rendered to
+	DirReplacements map[string]interface{} `yaml:"dirReplacements"`
+
+	// Migrated denotes if the repository was migrated to stencil
+	Migrated bool `yaml:"migrated"`
+
+	// PostRunCommand are the commands stencil runs after rendering
+	PostRunCommand []interface{} `yaml:"postRunCommand"`
+}
+
+// Validate strictly validates the contents of a configuration file: a
+// devenv.yaml, or a service.yaml when the base name of file is
+// service.yaml. Unknown keys, values of the wrong type, invalid
+// repository names and unknown resolution policies are reported, as
+// ValidationErrors, with their position in the file and the path of the
+// key they're about. service.yaml belongs to stencil, so only its devenv
+// keys are validated strictly, problems with the others are warnings,
+// see ValidationErrors.Split.
+func Validate(file string, b []byte) error {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(b, &doc); err != nil {
+		return ValidationErrors{yamlError(file, err)}
+	}
+	// An empty file is a valid, empty, configuration
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	root := doc.Content[0]
+	version := ""
+	if v := lookup(root, "apiVersion"); v != nil {
+		version = v.Value
+	}
+
+	// depLists returns the lists of dependencies of a node with
+	// dependencies, i.e. the root or a profile
+	depLists := func(n *yamlv3.Node) [][]*yamlv3.Node {
+		return [][]*yamlv3.Node{items(lookup(n, "dependencies", "required")), items(lookup(n, "dependencies", "optional"))}
+	}
+
+	var target interface{} = &Devenv{}
+	switch {
+	case filepath.Base(file) == "service.yaml":
+		target = &ServiceManifest{}
+	case version == APIVersionV2:
+		target = &DevenvV2{}
+		depLists = func(n *yamlv3.Node) [][]*yamlv3.Node {
+			return [][]*yamlv3.Node{items(lookup(n, "dependencies"))}
+		}
+	case version != "" && version != APIVersionV1:
+		v := lookup(root, "apiVersion")
+		return ValidationErrors{{File: file, Line: v.Line, Column: v.Column,
+			Msg: fmt.Sprintf("unknown apiVersion %q, expected one of [v1 v2]", version)}}
+	}
+
+	errs := make(ValidationErrors, 0)
+	dec := yamlv3.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	// decodeErr is an error that stopped decoding, which is only reported
+	// when there are no other errors
+	var decodeErr error
+	if err := dec.Decode(target); err != nil && !errors.Is(err, io.EOF) {
+		var terr *yamlv3.TypeError
+		if errors.As(err, &terr) {
+			for _, msg := range terr.Errors {
+				// Only invalid dependencies, which are reported with their
+				// position below, are errors without a line
+				if !typeErrorRe.MatchString(msg) {
+					continue
+				}
+				errs = append(errs, typeError(file, root, msg))
+			}
+		} else {
+			decodeErr = err
+		}
+	}
+
+	withDeps := []*yamlv3.Node{root}
+	profiles := lookup(root, "profiles")
+	if profiles != nil && profiles.Kind == yamlv3.MappingNode {
+		for i := 1; i < len(profiles.Content); i += 2 {
+			withDeps = append(withDeps, profiles.Content[i])
+		}
+	}
+	for _, n := range withDeps {
+		for _, deps := range depLists(n) {
+			for _, dep := range deps {
+				errs = append(errs, validateDependency(file, dep)...)
+			}
+		}
+	}
+
+	if _, severe := errs.Split(); decodeErr != nil && len(severe) == 0 {
+		return append(errs, yamlError(file, decodeErr))
+	}
+
+	// Profiles can only be resolved when the file could be decoded
+	if _, severe := errs.Split(); len(severe) == 0 && decodeErr == nil {
+		errs = append(errs, validateProfiles(file, target, profiles)...)
+	}
+
+	if p := lookup(root, "resolution", "optionalDependencies"); p != nil {
+		if err := OptionalPolicy(p.Value).Validate(); err != nil {
+			errs = append(errs, &ValidationError{File: file, Line: p.Line, Column: p.Column, Msg: err.Error()})
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateProfiles validates that every profile extends known profiles,
+// without cycles
+func validateProfiles(file string, target interface{}, profiles *yamlv3.Node) ValidationErrors {
+	var dc *Devenv
+	switch t := target.(type) {
+	case *Devenv:
+		dc = t
+	case *DevenvV2:
+		dc = t.Devenv()
+	default:
+		return nil
+	}
+
+	errs := make(ValidationErrors, 0)
+	for i := 0; profiles != nil && i+1 < len(profiles.Content); i += 2 {
+		key := profiles.Content[i]
+		if _, err := dc.WithProfile(key.Value); err != nil {
+			errs = append(errs, &ValidationError{File: file, Line: key.Line, Column: key.Column,
+				Msg: fmt.Sprintf("profile %q: %s", key.Value, err)})
+		}
+	}
+	return errs
+}
+
+// validateDependency validates the name, ref and readiness timeout of a
+// dependency in either the string or the mapping form
+func validateDependency(file string, dep *yamlv3.Node) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	name, ref := dep, dep
+	if dep.Kind == yamlv3.MappingNode {
+		name, ref = lookup(dep, "name"), lookup(dep, "ref")
+	}
+	if name != nil && name.Kind == yamlv3.ScalarNode {
+		// The string form may be pinned to a ref, name@ref
+		n, _, _ := strings.Cut(name.Value, "@")
+		if err := ValidateName(n); err != nil {
+			errs = append(errs, &ValidationError{File: file, Line: name.Line, Column: name.Column, Msg: err.Error()})
+		}
+	}
+	if ref != nil && ref.Kind == yamlv3.ScalarNode {
+		r := ref.Value
+		if ref == dep {
+			_, r, _ = strings.Cut(ref.Value, "@")
+		}
+		if err := ValidateRef(r); err != nil {
+			errs = append(errs, &ValidationError{File: file, Line: ref.Line, Column: ref.Column, Msg: err.Error()})
+		}
+	}
+
+	if t := lookup(dep, "readiness", "timeout"); t != nil {
+		if _, err := time.ParseDuration(t.Value); err != nil {
+			errs = append(errs, &ValidationError{File: file, Line: t.Line, Column: t.Column,
+				Msg: fmt.Sprintf("readiness timeout %q is not a valid duration, e.g. 5m", t.Value)})
+		}
+	}
+	return errs
+}
+
+// ValidateFiles validates the configuration files that exist out of the
+// given paths, returning the problems of all of them
+func ValidateFiles(paths ...string) error {
+	errs := make(ValidationErrors, 0)
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := Validate(path, b); err != nil {
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				return err
+			}
+			errs = append(errs, verrs...)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// typeError converts an error of a yaml.v3 TypeError into a
+// ValidationError about the key it's for, with the path of that key
+// instead of the Go type it's decoded into. In service.yaml, problems
+// outside of the devenv keys are warnings.
+func typeError(file string, root *yamlv3.Node, msg string) *ValidationError {
+	verr := yamlError(file, errors.New(msg))
+
+	var match func(key, value *yamlv3.Node) *yamlv3.Node
+	var describe func(path string) string
+	if m := unknownFieldRe.FindStringSubmatch(verr.Msg); m != nil {
+		match = func(key, _ *yamlv3.Node) *yamlv3.Node {
+			if key != nil && key.Line == verr.Line && key.Value == m[1] {
+				return key
+			}
+			return nil
+		}
+		describe = func(path string) string { return fmt.Sprintf("unknown key %q", path) }
+		verr.Msg = fmt.Sprintf("unknown key %q", m[1])
+	} else if m := wrongTypeRe.FindStringSubmatch(verr.Msg); m != nil {
+		match = func(_, value *yamlv3.Node) *yamlv3.Node {
+			if value.Line == verr.Line && value.ShortTag() == m[1] {
+				return value
+			}
+			return nil
+		}
+		kind, ok := yamlKinds[m[1]]
+		if !ok {
+			kind = m[1]
+		}
+		describe = func(path string) string { return fmt.Sprintf("%q can't be %s", path, kind) }
+		verr.Msg = fmt.Sprintf("the file can't be %s", kind)
+	} else {
+		return verr
+	}
+
+	path, n := findNode(root, "", match)
+	if n == nil {
+		return verr
+	}
+	verr.Column, verr.Msg = n.Column, describe(path)
+
+	if filepath.Base(file) == "service.yaml" {
+		key, _, _ := strings.Cut(path, ".")
+		key, _, _ = strings.Cut(key, "[")
+		verr.Warning = !devenvKeys()[key]
+	}
+	return verr
+}
+
+// findNode returns the node, and the path of keys to it, out of the
+// descendants of n for which match returns a node. match is called with
+// the keys and values of mappings, and with a nil key for the items of
+// sequences. Descendants are searched before their ancestors, returning
+// the innermost match.
+func findNode(n *yamlv3.Node, path string, match func(key, value *yamlv3.Node) *yamlv3.Node) (string, *yamlv3.Node) {
+	visit := func(p string, key, value *yamlv3.Node) (string, *yamlv3.Node) {
+		if fp, fn := findNode(value, p, match); fn != nil {
+			return fp, fn
+		}
+		return p, match(key, value)
+	}
+
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			p := n.Content[i].Value
+			if path != "" {
+				p = path + "." + p
+			}
+			if fp, fn := visit(p, n.Content[i], n.Content[i+1]); fn != nil {
+				return fp, fn
+			}
+		}
+	case yamlv3.SequenceNode:
+		for i, item := range n.Content {
+			if fp, fn := visit(fmt.Sprintf("%s[%d]", path, i), nil, item); fn != nil {
+				return fp, fn
+			}
+		}
+	}
+	return "", nil
+}
+
+// devenvKeys returns the top-level keys of devenv.yaml
+func devenvKeys() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(Devenv{})
+	for i := 0; i < t.NumField(); i++ {
+		if key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); key != "" && key != "-" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// yamlError converts an error returned by yaml.v3 into a ValidationError
+func yamlError(file string, err error) *ValidationError {
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	if m := typeErrorRe.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1]) //nolint:errcheck // Why: the regex only matches digits
+		return &ValidationError{File: file, Line: line, Msg: m[2]}
+	}
+	return &ValidationError{File: file, Msg: msg}
+}
+
+// lookup returns the value of the given path of keys in a mapping node,
+// or nil if there is none
+func lookup(n *yamlv3.Node, keys ...string) *yamlv3.Node {
+	for _, key := range keys {
+		if n == nil || n.Kind != yamlv3.MappingNode {
+			return nil
+		}
+
+		var next *yamlv3.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+// items returns the items of a sequence node, or nothing if it isn't one
+func items(n *yamlv3.Node) []*yamlv3.Node {
+	if n == nil || n.Kind != yamlv3.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
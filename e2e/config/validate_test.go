@@ -0,0 +1,164 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		conf string
+		errs []string
+	}{
+		{
+			name: "valid",
+			file: "devenv.yaml",
+			conf: "service: true\ndependencies:\n  required:\n    - a\n    - b@v1.2.3\n    - name: c\n      ref: main\n" +
+				"  optional:\n    - d\nresolution:\n  optionalDependencies: all\n",
+		},
+		{
+			name: "empty",
+			file: "devenv.yaml",
+		},
+		{
+			name: "unknown keys",
+			file: "devenv.yaml",
+			conf: "dependecies:\n  required:\n    - a\n",
+			errs: []string{`devenv.yaml:1:1: unknown key "dependecies"`},
+		},
+		{
+			name: "unknown nested keys",
+			file: "devenv.yaml",
+			conf: "dependencies:\n  requried:\n    - a\n  optional:\n    - name: b\n      rev: main\n",
+			errs: []string{
+				`devenv.yaml:2:3: unknown key "dependencies.requried"`,
+				`devenv.yaml:6:7: unknown key "dependencies.optional[0].rev"`,
+			},
+		},
+		{
+			name: "invalid names and policy",
+			file: "devenv.yaml",
+			conf: "dependencies:\n  required:\n    - a/b\n    - name: ..\nresolution:\n  optionalDependencies: some\n",
+			errs: []string{
+				`devenv.yaml:3:7: dependency "a/b" is not a valid repository name`,
+				`devenv.yaml:4:13: dependency ".." is not a valid repository name`,
+				`devenv.yaml:6:25: unknown optional dependency policy "some", expected one of [none root all]`,
+			},
+		},
+		{
+			name: "invalid refs",
+			file: "devenv.yaml",
+			conf: "dependencies:\n  required:\n    - a@--upload-pack=touch\n    - b@../../x\n    - name: c\n      ref: main:x\n" +
+				"    - d@v1 2\n",
+			errs: []string{
+				`devenv.yaml:3:7: ref "--upload-pack=touch" must not start with a dash`,
+				`devenv.yaml:4:7: ref "../../x" must not contain ".."`,
+				`devenv.yaml:6:12: ref "main:x" must not contain ':'`,
+				`devenv.yaml:7:7: ref "v1 2" must not contain ' '`,
+			},
+		},
+		{
+			name: "traversal names",
+			file: "devenv.yaml",
+			conf: "apiVersion: v2\ndependencies:\n  - name: ../../..\n    ref: main\n  - ..@main\n  - name: b\n    rev: main\n",
+			errs: []string{
+				`devenv.yaml:7:5: unknown key "dependencies[2].rev"`,
+				`devenv.yaml:3:11: dependency "../../.." is not a valid repository name`,
+				`devenv.yaml:5:5: dependency ".." is not a valid repository name`,
+			},
+		},
+		{
+			name: "service.yaml",
+			file: "service.yaml",
+			conf: "name: app\narguments:\n  service: true\nmodules:\n  - name: stencil-base\ndependencies:\n  required:\n    - a\n",
+		},
+		{
+			name: "service.yaml unknown keys",
+			file: "service.yaml",
+			conf: "name: app\nargumetns: {}\nmodules:\n  - name: stencil-base\n    foo: bar\ndependencies:\n  requried: [a]\n",
+			errs: []string{
+				`service.yaml:2:1: warning: unknown key "argumetns"`,
+				`service.yaml:5:5: warning: unknown key "modules[0].foo"`,
+				`service.yaml:7:3: unknown key "dependencies.requried"`,
+			},
+		},
+		{
+			name: "wrong types",
+			file: "devenv.yaml",
+			conf: "service: [a]\ndependencies:\n  required:\n    - name: {a: b}\n",
+			errs: []string{
+				`devenv.yaml:1:10: "service" can't be a list`,
+				`devenv.yaml:4:13: "dependencies.required[0].name" can't be a mapping`,
+			},
+		},
+		{
+			name: "wrong type of the file",
+			file: "devenv.yaml",
+			conf: "- a\n",
+			errs: []string{"devenv.yaml:1: the file can't be a list"},
+		},
+		{
+			name: "v2",
+			file: "devenv.yaml",
+			conf: "apiVersion: v2\nservice: true\ndependencies:\n  - a\n  - name: b\n    optional: true\n" +
+				"    provisionTarget: flagship\n    readiness:\n      timeout: 5m\n",
+		},
+		{
+			name: "v2 invalid",
+			file: "devenv.yaml",
+			conf: "apiVersion: v2\ndependencies:\n  - a b\n  - name: c\n    readiness:\n      timeout: soon\n" +
+				"    requried: true\n",
+			errs: []string{
+				`devenv.yaml:7:5: unknown key "dependencies[1].requried"`,
+				`devenv.yaml:3:5: dependency "a b" is not a valid repository name`,
+				`devenv.yaml:6:16: readiness timeout "soon" is not a valid duration, e.g. 5m`,
+			},
+		},
+		{
+			name: "unknown apiVersion",
+			file: "devenv.yaml",
+			conf: "apiVersion: v3\n",
+			errs: []string{`devenv.yaml:1:13: unknown apiVersion "v3", expected one of [v1 v2]`},
+		},
+		{
+			name: "profiles",
+			file: "devenv.yaml",
+			conf: "profiles:\n  minimal:\n    extends: [missing]\n    dependencies:\n      required: [a/b]\n",
+			errs: []string{`devenv.yaml:5:18: dependency "a/b" is not a valid repository name`},
+		},
+		{
+			name: "profiles extends",
+			file: "devenv.yaml",
+			conf: "profiles:\n  minimal:\n    extends: [missing]\n",
+			errs: []string{`devenv.yaml:2:3: profile "minimal": unknown profile "missing", expected one of [default minimal]`},
+		},
+		{
+			name: "invalid yaml",
+			file: "devenv.yaml",
+			conf: "dependencies: [\n",
+			errs: []string{"devenv.yaml:1: did not find expected node content"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.file, []byte(tt.conf))
+			if len(tt.errs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var errs ValidationErrors
+			if assert.ErrorAs(t, err, &errs) {
+				msgs := make([]string, len(errs))
+				for i, e := range errs {
+					msgs[i] = e.Error()
+				}
+				assert.Equal(t, tt.errs, msgs)
+			}
+		})
+	}
+}
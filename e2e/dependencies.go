@@ -0,0 +1,290 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the dependency handling of the e2e runner.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/e2e/deps"
+	"github.com/getoutreach/gobox/pkg/box"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// pinnedCheckoutDir is the directory pinned dependencies are checked out in
+const pinnedCheckoutDir = "./bin/e2e-pinned"
+
+// dependencyGraph lazily resolves, and memoizes, the dependency graph of
+// the current application
+type dependencyGraph struct {
+	conf *box.Config
+	opts *deps.Options
+	g    *deps.Graph
+
+	// ex executes the commands that deploy the dependencies
+	ex executor
+
+	// waves deploys the dependencies in waves, following the deploy plan,
+	// instead of leaving it to `devenv apps deploy --with-deps`
+	waves bool
+
+	// pinned denotes if the devenv.yaml of the application pins a
+	// dependency to a ref, deploying it needs the graph then
+	pinned bool
+
+	// readyTimeout is how long a wave may take to become ready
+	readyTimeout time.Duration
+}
+
+// Needed returns whether deploying the dependencies needs the graph:
+// when deploying them in waves or those of a profile, or when the
+// application pins a dependency
+func (d *dependencyGraph) Needed() bool {
+	return d.waves || d.opts.Profile != "" || d.pinned
+}
+
+// Get returns the dependency graph, resolving it on first use
+func (d *dependencyGraph) Get(ctx context.Context) (*deps.Graph, error) {
+	if d.g != nil {
+		return d.g, nil
+	}
+
+	g, err := deps.ResolveCurrent(ctx, d.conf, d.opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to build dependency tree")
+	}
+	d.g = g
+	return g, nil
+}
+
+// printDependencyGraph writes the dependency graph to stdout in the given format
+func printDependencyGraph(ctx context.Context, dg *dependencyGraph, format string) error {
+	g, err := dg.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return g.Write(os.Stdout, format)
+}
+
+// provisionTargetConfigs returns the provision target configuration of
+// devenv.yaml and box, in that order. Configuration that can't be read is
+// skipped.
+func provisionTargetConfigs() []config.ProvisionTargets {
+	confs := make([]config.ProvisionTargets, 0)
+	if dc, err := config.FromFile("devenv.yaml"); err == nil {
+		confs = append(confs, dc.ProvisionTargets)
+	}
+
+	bd, err := config.LoadBoxDevbase()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read provision target rules from box, ignoring them")
+		return confs
+	}
+	return append(confs, bd.ProvisionTargets)
+}
+
+// deployWithDependencies deploys app, a service name or path, together
+// with its dependencies. When a profile is used, and not deploying in
+// waves, its top-level dependencies are deployed with theirs, as `devenv
+// apps deploy --with-deps` only knows the top-level dependencies of
+// devenv.yaml.
+func deployWithDependencies(ctx context.Context, dg *dependencyGraph, app string) error {
+	switch {
+	case dg.waves:
+		if err := deployInWaves(ctx, dg); err != nil {
+			return err
+		}
+		return dg.ex.Run(ctx, stdInOutErr("devenv", "--skip-update", "apps", "deploy", app))
+	case dg.opts.Profile != "":
+		if err := deployProfileDependencies(ctx, dg); err != nil {
+			return err
+		}
+		if err := dg.ex.Run(ctx, stdInOutErr("devenv", "--skip-update", "apps", "deploy", app)); err != nil {
+			return err
+		}
+	default:
+		if err := dg.ex.Run(ctx, stdInOutErr("devenv", "--skip-update", "apps", "deploy", "--with-deps", app)); err != nil {
+			return err
+		}
+	}
+	return deployPinnedDependencies(ctx, dg)
+}
+
+// deployProfileDependencies deploys the top-level dependencies of the
+// profile, each with its dependencies
+func deployProfileDependencies(ctx context.Context, dg *dependencyGraph) error {
+	g, err := dg.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	top := g.DependenciesOf(g.Root)
+	log.Info().Str("profile", dg.opts.Profile).Strs("deps", top).
+		Msg("Deploying the top-level dependencies of the profile with their dependencies")
+	for _, name := range top {
+		if err := dg.ex.Run(ctx, stdInOutErr("devenv", "--skip-update", "apps", "deploy", "--with-deps", name)); err != nil {
+			return errors.Wrapf(err, "Failed to deploy %s into devenv", name)
+		}
+	}
+	return nil
+}
+
+// deployPinnedDependencies deploys the dependencies that are pinned to a
+// ref from a checkout of their repository at that ref. This is done after
+// `devenv apps deploy --with-deps`, which deploys the latest version of
+// every dependency, to replace those versions. Resolving the graph needs
+// access to the forge, so it's only done for pins of the application
+// itself, pins of its dependencies alone are only deployed when the graph
+// was resolved for something else, e.g. provisioning the devenv.
+func deployPinnedDependencies(ctx context.Context, dg *dependencyGraph) error {
+	if dg.g == nil && !dg.Needed() {
+		return nil
+	}
+
+	g, err := dg.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range g.Pinned() {
+		if err := deployDependency(ctx, dg, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deployInWaves deploys the dependencies one wave of the deploy plan at a
+// time, waiting for the deployments of a wave to become available before
+// deploying the next wave
+func deployInWaves(ctx context.Context, dg *dependencyGraph) error {
+	g, err := dg.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	readiness := boxReadiness()
+	p := g.Plan()
+	if len(p.Cyclic) > 0 {
+		log.Warn().Strs("deps", p.Cyclic).Msg("Dependencies with cyclical dependencies are deployed in the last wave")
+	}
+
+	for i, wave := range p.Waves {
+		log.Info().Int("wave", i+1).Int("waves", len(p.Waves)).Strs("deps", wave).Msg("Deploying wave of dependencies")
+		for _, name := range wave {
+			if err := deployDependency(ctx, dg, g.Node(name)); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range wave {
+			if err := waitForReady(ctx, dg.ex, g.Node(name), readiness, dg.readyTimeout); err != nil {
+				return errors.Wrapf(err, "%s did not become ready", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// deployDependency deploys a single dependency, without its dependencies.
+// Dependencies pinned to a ref are deployed from a checkout of that ref.
+func deployDependency(ctx context.Context, dg *dependencyGraph, n *deps.Node) error {
+	if n.Ref == "" {
+		log.Info().Str("dep", n.Name).Msg("Deploying dependency")
+		if err := dg.ex.Run(ctx, stdInOutErr("devenv", "--skip-update", "apps", "deploy", n.Name)); err != nil {
+			return errors.Wrapf(err, "Failed to deploy %s into devenv", n.Name)
+		}
+		return nil
+	}
+
+	dir, err := filepath.Abs(filepath.Join(pinnedCheckoutDir, n.Name))
+	if err != nil {
+		return err
+	}
+
+	url, err := dg.opts.CloneURL(dg.conf, n.Name)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("dep", n.Name).Str("ref", n.Ref).Msg("Checking out pinned dependency")
+	if err := checkoutRef(ctx, dg.ex, url, n.Ref, dir); err != nil {
+		return errors.Wrapf(err, "failed to checkout %s@%s", n.Name, n.Ref)
+	}
+
+	log.Info().Str("dep", n.Name).Str("ref", n.Ref).Msg("Deploying pinned dependency")
+	if err := dg.ex.Run(ctx, stdInOutErr("devenv", "--skip-update", "apps", "deploy", dir)); err != nil {
+		return errors.Wrapf(err, "Failed to deploy %s@%s into devenv", n.Name, n.Ref)
+	}
+	return nil
+}
+
+// boxReadiness returns the readiness configuration of box. Configuration
+// that can't be read is ignored, using the defaults.
+func boxReadiness() *config.BoxReadiness {
+	bd, err := config.LoadBoxDevbase()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read readiness configuration from box, using the defaults")
+		return &config.BoxReadiness{}
+	}
+	return &bd.Readiness
+}
+
+// waitForReady waits for the deployments of a service to become
+// available, all of them unless its readiness configuration lists
+// specific deployments. The deployments are looked for in the namespace
+// of the box configuration, unless the readiness configuration overrides
+// it, as it can override the timeout.
+func waitForReady(ctx context.Context, ex executor, n *deps.Node, readiness *config.BoxReadiness,
+	timeout time.Duration) error {
+	deployments := []string{"--all"}
+	namespace := readiness.NamespaceOf(n.Name)
+	if n.Readiness != nil {
+		if n.Readiness.Namespace != "" {
+			namespace = n.Readiness.Namespace
+		}
+		if len(n.Readiness.Deployments) > 0 {
+			deployments = n.Readiness.Deployments
+		}
+		if n.Readiness.Timeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(n.Readiness.Timeout); err != nil {
+				return errors.Wrapf(err, "invalid readiness timeout of %s", n.Name)
+			}
+		}
+	}
+
+	log.Info().Str("dep", n.Name).Msg("Waiting for dependency to become ready")
+	args := append([]string{"wait", "deployments"}, deployments...)
+	args = append(args, "--namespace", namespace, "--for=condition=Available", "--timeout="+timeout.String())
+	return ex.Run(ctx, stdOutErr("kubectl", args...))
+}
+
+// checkoutRef creates a shallow checkout of a repository at the given ref
+// in dir, replacing anything that's there. Fetching the ref, instead of
+// cloning, works for branches, tags and commit SHAs alike.
+func checkoutRef(ctx context.Context, ex executor, remote, ref, dir string) error {
+	for _, c := range []*command{
+		newCommand("rm", "-rf", dir),
+		newCommand("git", "init", "--quiet", dir),
+		newCommand("git", "-C", dir, "remote", "add", "origin", remote),
+		newCommand("git", "-C", dir, "fetch", "--quiet", "--depth", "1", "--end-of-options", "origin", ref),
+		newCommand("git", "-C", dir, "checkout", "--quiet", "FETCH_HEAD"),
+	} {
+		if out, err := ex.Output(ctx, c); err != nil {
+			return fmt.Errorf("%s: %s", c, out)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/e2e/deps"
+	"github.com/getoutreach/gobox/pkg/box"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForReady(t *testing.T) {
+	tests := []struct {
+		name      string
+		readiness *config.Readiness
+		box       config.BoxReadiness
+		want      string
+	}{
+		{
+			name: "defaults",
+			want: "kubectl wait deployments --all --namespace mint--bento1a --for=condition=Available --timeout=10m0s",
+		},
+		{
+			name: "box namespace",
+			box:  config.BoxReadiness{Namespace: "apps-{name}"},
+			want: "kubectl wait deployments --all --namespace apps-mint --for=condition=Available --timeout=10m0s",
+		},
+		{
+			name:      "dependency readiness",
+			readiness: &config.Readiness{Deployments: []string{"mint-api"}, Timeout: "5m", Namespace: "mint"},
+			box:       config.BoxReadiness{Namespace: "apps-{name}"},
+			want:      "kubectl wait deployments mint-api --namespace mint --for=condition=Available --timeout=5m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex := &fakeExecutor{}
+			n := &deps.Node{Name: "mint", Readiness: tt.readiness}
+			box := tt.box
+			assert.NoError(t, waitForReady(context.Background(), ex, n, &box, 10*time.Minute))
+			assert.Equal(t, []string{tt.want}, ex.cmds)
+		})
+	}
+}
+
+func TestDeployPinnedDependency(t *testing.T) {
+	ex := &fakeExecutor{}
+	dg := &dependencyGraph{conf: &box.Config{Org: "getoutreach"}, opts: &deps.Options{}, ex: ex}
+	g := deps.NewGraph("app", "devenv.yaml")
+	n := g.AddNode("mint", "devenv.yaml")
+	n.Ref = "v1"
+
+	// Only the clone URL is needed, not credentials of the forge
+	assert.NoError(t, deployDependency(context.Background(), dg, n))
+	dir, err := filepath.Abs(filepath.Join(pinnedCheckoutDir, "mint"))
+	assert.NoError(t, err)
+	assert.Contains(t, ex.cmds, "git -C "+dir+" remote add origin git@github.com:getoutreach/mint.git")
+	assert.Contains(t, ex.cmds, "devenv --skip-update apps deploy "+dir)
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the loading of the alias table of service names.
+
+package deps
+
+import (
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultAliases are the built-in aliases, which configured aliases are
+// put on top of. An alias configured to an empty name disables it.
+var DefaultAliases = config.Aliases{flagship: "outreach"}
+
+// LoadAliases returns DefaultAliases overlaid with the aliases configured
+// in box, overlaid with those configured in the given devenv.yaml
+func LoadAliases(dc *config.Devenv) config.Aliases {
+	aliases := DefaultAliases
+	if bd, err := config.LoadBoxDevbase(); err != nil {
+		log.Warn().Err(err).Msg("Failed to read aliases from box, ignoring them")
+	} else {
+		aliases = aliases.Merge(bd.Aliases)
+	}
+	return aliases.Merge(dc.Aliases)
+}
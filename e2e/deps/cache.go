@@ -0,0 +1,210 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains an on-disk cache for configuration read from a source.
+
+package deps
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultCacheTTL is the default duration cached configuration is used
+// without checking if its repository has changed
+const DefaultCacheTTL = time.Hour
+
+// cacheEntry is a cached file of a service, stored as JSON
+type cacheEntry struct {
+	// Revision is the revision of the repository the file was read at,
+	// empty if the source could not provide one
+	Revision string `json:"revision"`
+
+	// FetchedAt is when the entry was last read, or validated, from the source
+	FetchedAt time.Time `json:"fetchedAt"`
+
+	// Found denotes if the file existed, negative results are cached as
+	// well as most services only contain one of PossibleFiles
+	Found bool `json:"found"`
+
+	// Content is the contents of the file
+	Content []byte `json:"content,omitempty"`
+}
+
+// CachedSource is a Source that caches files read from another Source on
+// disk, keyed by org, service, ref, file and the revision of the repository.
+// Entries younger than the TTL are used as-is, older entries are used
+// only if the revision of the repository, if the underlying source is a
+// Revisioner, hasn't changed.
+type CachedSource struct {
+	src     Source
+	dir     string
+	org     string
+	ttl     time.Duration
+	refresh bool
+
+	// revisions memoizes the current revision of each service and ref,
+	// so the revision is only looked up once for all of PossibleFiles
+	mu        sync.Mutex
+	revisions map[string]string
+}
+
+// DefaultCacheDir returns the directory configuration is cached in
+func DefaultCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to determine home directory")
+	}
+	return filepath.Join(homeDir, ".outreach", ".cache", "devbase", "e2e-deps"), nil
+}
+
+// NewCachedSource creates a CachedSource caching files read from src in
+// dir. When refresh is set, existing entries are ignored and replaced.
+func NewCachedSource(src Source, dir, org string, ttl time.Duration, refresh bool) *CachedSource {
+	return &CachedSource{
+		src:       src,
+		dir:       dir,
+		org:       org,
+		ttl:       ttl,
+		refresh:   refresh,
+		revisions: make(map[string]string),
+	}
+}
+
+// Name implements Source
+func (s *CachedSource) Name() string {
+	return s.src.Name()
+}
+
+// ReadFile implements Source
+func (s *CachedSource) ReadFile(ctx context.Context, serviceName, file, ref string) ([]byte, error) {
+	l := log.With().Str("service", serviceName).Str("file", file).Str("ref", ref).Logger()
+
+	path := filepath.Join(s.dir, s.org, serviceName, cacheRefKey(ref), file+".json")
+
+	var entry *cacheEntry
+	if !s.refresh {
+		entry = readCacheEntry(path)
+	}
+	if entry != nil && time.Since(entry.FetchedAt) < s.ttl {
+		l.Debug().Msg("Using cached file")
+		return entry.result()
+	}
+
+	lastKnown := ""
+	if entry != nil {
+		lastKnown = entry.Revision
+	}
+	rev := s.revision(ctx, serviceName, ref, lastKnown)
+
+	if entry != nil && rev != "" && entry.Revision == rev {
+		l.Debug().Str("revision", rev).Msg("Using cached file, revision unchanged")
+		entry.FetchedAt = time.Now()
+		writeCacheEntry(path, entry)
+		return entry.result()
+	}
+
+	b, err := s.src.ReadFile(ctx, serviceName, file, ref)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	writeCacheEntry(path, &cacheEntry{
+		Revision:  rev,
+		FetchedAt: time.Now(),
+		Found:     err == nil,
+		Content:   b,
+	})
+	return b, err
+}
+
+// cacheRefKey returns the path element the entries of a ref are stored
+// in: HEAD for the default branch, otherwise a hash of the ref, as refs
+// may contain slashes, or dots, that would escape the directory of the
+// ref or collide with the entries of another ref
+func cacheRefKey(ref string) string {
+	if ref == "" {
+		return "HEAD"
+	}
+	sum := sha256.Sum256([]byte(ref))
+	return "ref-" + hex.EncodeToString(sum[:])
+}
+
+// revision returns the current revision of a ref of the repository of a
+// service, or an empty string if it can't be determined
+func (s *CachedSource) revision(ctx context.Context, serviceName, ref, lastKnown string) string {
+	r, ok := s.src.(Revisioner)
+	if !ok {
+		return ""
+	}
+
+	key := serviceName + "@" + ref
+	s.mu.Lock()
+	rev, ok := s.revisions[key]
+	s.mu.Unlock()
+	if ok {
+		return rev
+	}
+
+	rev, err := r.Revision(ctx, serviceName, ref, lastKnown)
+	if err != nil {
+		log.Debug().Err(err).Str("service", serviceName).Msg("Unable to determine revision")
+		rev = ""
+	}
+
+	s.mu.Lock()
+	s.revisions[key] = rev
+	s.mu.Unlock()
+	return rev
+}
+
+// result returns the cached file as returned by Source.ReadFile
+func (e *cacheEntry) result() ([]byte, error) {
+	if !e.Found {
+		return nil, ErrNotFound
+	}
+	return e.Content, nil
+}
+
+// readCacheEntry reads a cache entry, returning nil if it doesn't exist
+// or can't be read
+func readCacheEntry(path string) *cacheEntry {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		log.Debug().Err(err).Str("path", path).Msg("Ignoring invalid cache entry")
+		return nil
+	}
+	return &entry
+}
+
+// writeCacheEntry writes a cache entry, failures are logged and otherwise
+// ignored as the cache is best effort
+func writeCacheEntry(path string, entry *cacheEntry) {
+	b, err := json.Marshal(entry)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		// Write to a temporary file first to not leave partial entries behind
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, b, 0o600); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to write dependency cache entry")
+	}
+}
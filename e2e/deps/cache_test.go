@@ -0,0 +1,100 @@
+package deps
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// revisionedSource is a memorySource with a revision that counts reads
+type revisionedSource struct {
+	memorySource
+
+	rev   string
+	reads int
+}
+
+func (s *revisionedSource) ReadFile(ctx context.Context, serviceName, file, ref string) ([]byte, error) {
+	s.reads++
+	return s.memorySource.ReadFile(ctx, serviceName, file, ref)
+}
+
+func (s *revisionedSource) Revision(_ context.Context, _, _, _ string) (string, error) {
+	return s.rev, nil
+}
+
+func TestCachedSource(t *testing.T) {
+	dir := t.TempDir()
+	src := &revisionedSource{
+		memorySource: memorySource{name: "github", files: map[string]map[string]string{
+			"a": {"devenv.yaml": "service: true"},
+		}},
+		rev: "sha1",
+	}
+	ctx := context.Background()
+
+	read := func(ttl time.Duration, refresh bool, file string) ([]byte, error) {
+		return NewCachedSource(src, dir, "getoutreach", ttl, refresh).ReadFile(ctx, "a", file, "")
+	}
+
+	b, err := read(time.Hour, false, "devenv.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "service: true", string(b))
+	_, err = read(time.Hour, false, "service.yaml")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 2, src.reads)
+
+	// Within the TTL, including negative results
+	b, err = read(time.Hour, false, "devenv.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "service: true", string(b))
+	_, err = read(time.Hour, false, "service.yaml")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 2, src.reads)
+
+	// Expired, but the revision is unchanged
+	_, err = read(0, false, "devenv.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, src.reads)
+
+	// Expired, and the revision changed
+	src.rev = "sha2"
+	src.files["a"]["devenv.yaml"] = "service: false"
+	b, err = read(0, false, "devenv.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "service: false", string(b))
+	assert.Equal(t, 3, src.reads)
+
+	// Refresh ignores the cache
+	_, err = read(time.Hour, true, "devenv.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, src.reads)
+}
+
+func TestCachedSourceRefs(t *testing.T) {
+	dir := t.TempDir()
+	src := &revisionedSource{memorySource: memorySource{name: "github", files: map[string]map[string]string{
+		"a": {"devenv.yaml": "service: true"},
+	}}}
+	ctx := context.Background()
+
+	for _, ref := range []string{"..", "../..", "feature/x", "feature%2Fx", "HEAD"} {
+		// None of the refs exist, which is cached as well
+		_, err := NewCachedSource(src, dir, "getoutreach", time.Hour, false).ReadFile(ctx, "a", "devenv.yaml", ref)
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	assert.Equal(t, 5, src.reads, "every ref should have its own entry")
+
+	// Every entry is stored in a directory of its ref, within the one of the service
+	entries, err := os.ReadDir(filepath.Join(dir, "getoutreach", "a"))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 5)
+	for _, e := range entries {
+		_, err := os.Stat(filepath.Join(dir, "getoutreach", "a", e.Name(), "devenv.yaml.json"))
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the diff between two dependency graphs.
+
+package deps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+)
+
+// Change is a dependency that was added, removed or changed between two
+// dependency graphs
+type Change struct {
+	// Name is the name of the service
+	Name string `json:"name"`
+
+	// Ref is the ref the service is pinned to in the graph it's in, for
+	// changed services the ref in the head graph
+	Ref string `json:"ref,omitempty"`
+
+	// BaseRef is the ref a changed service was pinned to in the base graph
+	BaseRef string `json:"baseRef,omitempty"`
+
+	// Path is the path of dependencies from the root that pulls in the
+	// service, in the graph it's in
+	Path []string `json:"path"`
+}
+
+// Diff is the difference between the dependencies of two graphs
+type Diff struct {
+	// Added are the services only in the head graph
+	Added []Change `json:"added"`
+
+	// Removed are the services only in the base graph
+	Removed []Change `json:"removed"`
+
+	// Changed are the services pinned to a different ref
+	Changed []Change `json:"changed"`
+}
+
+// DiffGraphs computes which dependencies were added, removed or pinned to
+// a different ref going from the base to the head graph
+func DiffGraphs(base, head *Graph) *Diff {
+	d := &Diff{Added: make([]Change, 0), Removed: make([]Change, 0), Changed: make([]Change, 0)}
+	for _, name := range head.Services() {
+		n := head.Node(name)
+		if !base.Has(name) {
+			d.Added = append(d.Added, Change{Name: name, Ref: n.Ref, Path: head.PathTo(name)})
+			continue
+		}
+
+		if b := base.Node(name); b.Ref != n.Ref {
+			d.Changed = append(d.Changed, Change{Name: name, Ref: n.Ref, BaseRef: b.Ref, Path: head.PathTo(name)})
+		}
+	}
+
+	for _, name := range base.Services() {
+		if !head.Has(name) {
+			d.Removed = append(d.Removed, Change{Name: name, Ref: base.Node(name).Ref, Path: base.PathTo(name)})
+		}
+	}
+
+	return d
+}
+
+// Empty returns whether there are no differences
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Write writes the diff in the given format, either "text" or "json"
+func (d *Diff) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		var b strings.Builder
+		if d.Empty() {
+			b.WriteString("no dependency changes\n")
+		}
+		for _, c := range d.Added {
+			fmt.Fprintf(&b, "+ %s (%s)\n", config.Dependency{Name: c.Name, Ref: c.Ref}, strings.Join(c.Path, " -> "))
+		}
+		for _, c := range d.Removed {
+			fmt.Fprintf(&b, "- %s (%s)\n", config.Dependency{Name: c.Name, Ref: c.Ref}, strings.Join(c.Path, " -> "))
+		}
+		for _, c := range d.Changed {
+			fmt.Fprintf(&b, "~ %s -> %s (%s)\n", config.Dependency{Name: c.Name, Ref: c.BaseRef},
+				config.Dependency{Name: c.Name, Ref: c.Ref}, strings.Join(c.Path, " -> "))
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	default:
+		return fmt.Errorf("unknown diff format %q, expected one of [text json]", format)
+	}
+}
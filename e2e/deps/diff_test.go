@@ -0,0 +1,46 @@
+package deps
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffGraphs(t *testing.T) {
+	base := NewGraph("app", "devenv.yaml")
+	base.AddNode("a", "devenv.yaml")
+	base.AddNode("b", "devenv.yaml")
+	base.AddNode("old", "devenv.yaml")
+	base.AddEdge("app", "a", true)
+	base.AddEdge("a", "b", true)
+	base.AddEdge("b", "old", true)
+
+	head := NewGraph("app", "devenv.yaml")
+	head.AddNode("a", "devenv.yaml")
+	head.AddNode("b", "devenv.yaml").Ref = "v2"
+	head.AddNode("c", "devenv.yaml")
+	head.AddNode("new", "devenv.yaml")
+	head.AddEdge("app", "a", true)
+	head.AddEdge("a", "b", true)
+	head.AddEdge("app", "c", false)
+	head.AddEdge("c", "new", true)
+	head.AddEdge("b", "new", true)
+
+	d := DiffGraphs(base, head)
+	assert.Equal(t, []Change{
+		{Name: "c", Path: []string{"app", "c"}},
+		{Name: "new", Path: []string{"app", "c", "new"}},
+	}, d.Added)
+	assert.Equal(t, []Change{{Name: "old", Path: []string{"app", "a", "b", "old"}}}, d.Removed)
+	assert.Equal(t, []Change{{Name: "b", Ref: "v2", Path: []string{"app", "a", "b"}}}, d.Changed)
+
+	var buf bytes.Buffer
+	assert.NoError(t, d.Write(&buf, "text"))
+	assert.Equal(t, "+ c (app -> c)\n+ new (app -> c -> new)\n- old (app -> a -> b -> old)\n~ b -> b@v2 (app -> a -> b)\n",
+		buf.String())
+
+	buf.Reset()
+	assert.NoError(t, DiffGraphs(base, base).Write(&buf, "text"))
+	assert.Equal(t, "no dependency changes\n", buf.String())
+}
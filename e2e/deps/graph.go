@@ -0,0 +1,332 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the dependency graph produced by the resolver.
+
+package deps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+)
+
+// Node is a service in the dependency graph
+type Node struct {
+	// Name is the name of the service (repository)
+	Name string `json:"name"`
+
+	// Ref is the git ref the service is pinned to, empty for the
+	// default branch
+	Ref string `json:"ref,omitempty"`
+
+	// Source is the file the dependencies of this service were read from,
+	// e.g. devenv.yaml. Empty when no configuration file could be found.
+	Source string `json:"source,omitempty"`
+
+	// Origin is the name of the Source the configuration file was read
+	// from, e.g. github. Empty for the root application.
+	Origin string `json:"origin,omitempty"`
+
+	// ProvisionTarget is the devenv provision target the service needs,
+	// empty when it has no preference
+	ProvisionTarget string `json:"provisionTarget,omitempty"`
+
+	// Readiness configures how to check if the service is ready after it
+	// has been deployed, nil for the default check
+	Readiness *config.Readiness `json:"readiness,omitempty"`
+}
+
+// Edge is a dependency of one service on another
+type Edge struct {
+	// From is the name of the service that declared the dependency
+	From string `json:"from"`
+
+	// To is the name of the service that is depended on
+	To string `json:"to"`
+
+	// Required denotes if this is a required, rather than optional, dependency
+	Required bool `json:"required"`
+}
+
+// Graph is a resolved dependency tree of a root application
+type Graph struct {
+	// Root is the name of the application the graph was resolved for
+	Root string
+
+	nodes map[string]*Node
+	edges map[Edge]struct{}
+
+	// aliases are the aliases the names of the dependencies were resolved
+	// with, see config.Aliases
+	aliases config.Aliases
+}
+
+// NewGraph creates an empty graph for the given root application
+func NewGraph(root, source string) *Graph {
+	g := &Graph{
+		Root:  root,
+		nodes: make(map[string]*Node),
+		edges: make(map[Edge]struct{}),
+	}
+	g.AddNode(root, source)
+	return g
+}
+
+// AddNode adds a node to the graph, if it doesn't already exist. The
+// source of an existing node is only updated if it's currently unset.
+func (g *Graph) AddNode(name, source string) *Node {
+	n, ok := g.nodes[name]
+	if !ok {
+		n = &Node{Name: name}
+		g.nodes[name] = n
+	}
+	if n.Source == "" {
+		n.Source = source
+	}
+	return n
+}
+
+// AddEdge records that from depends on to
+func (g *Graph) AddEdge(from, to string, required bool) {
+	g.edges[Edge{From: from, To: to, Required: required}] = struct{}{}
+}
+
+// Has returns true if the graph contains the given service
+func (g *Graph) Has(name string) bool {
+	_, ok := g.nodes[name]
+	return ok
+}
+
+// Node returns the node of the given service, or nil if it's not
+// part of the graph
+func (g *Graph) Node(name string) *Node {
+	return g.nodes[name]
+}
+
+// Nodes returns all nodes of the graph, sorted by name
+func (g *Graph) Nodes() []*Node {
+	nodes := make([]*Node, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes
+}
+
+// Edges returns all edges of the graph, sorted by source and then target
+func (g *Graph) Edges() []Edge {
+	edges := make([]Edge, 0, len(g.edges))
+	for e := range g.edges {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		if edges[i].To != edges[j].To {
+			return edges[i].To < edges[j].To
+		}
+		return edges[i].Required && !edges[j].Required
+	})
+	return edges
+}
+
+// Pinned returns the dependencies of the root application that are
+// pinned to a ref, sorted by name
+func (g *Graph) Pinned() []*Node {
+	pinned := make([]*Node, 0)
+	for _, n := range g.Nodes() {
+		if n.Ref != "" && n.Name != g.Root {
+			pinned = append(pinned, n)
+		}
+	}
+	return pinned
+}
+
+// Services returns the names of all of the dependencies of the root
+// application, sorted by name. The root itself is not included.
+func (g *Graph) Services() []string {
+	services := make([]string, 0, len(g.nodes))
+	for name := range g.nodes {
+		if name == g.Root {
+			continue
+		}
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// DependenciesOf returns the names of the direct dependencies of the
+// given service, sorted by name
+func (g *Graph) DependenciesOf(name string) []string {
+	deps := make([]string, 0)
+	for _, e := range g.Edges() {
+		if e.From == name && (len(deps) == 0 || deps[len(deps)-1] != e.To) {
+			deps = append(deps, e.To)
+		}
+	}
+	return deps
+}
+
+// MarshalJSON implements json.Marshaler
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Root  string  `json:"root"`
+		Nodes []*Node `json:"nodes"`
+		Edges []Edge  `json:"edges"`
+	}{g.Root, g.Nodes(), g.Edges()})
+}
+
+// WriteJSON writes the graph as indented JSON
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// WriteDOT writes the graph in the graphviz DOT format. Required
+// dependencies are drawn as solid edges, optional ones as dashed edges.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, n := range g.Nodes() {
+		label := dotEscape(n.Name)
+		if n.Ref != "" {
+			label += "@" + dotEscape(n.Ref)
+		}
+		if n.Source != "" {
+			label += `\n(` + dotEscape(n.Source) + ")"
+		}
+		attrs := `label="` + label + `"`
+		if n.Name == g.Root {
+			attrs += ", shape=box"
+		}
+		fmt.Fprintf(&b, "  \"%s\" [%s];\n", dotEscape(n.Name), attrs)
+	}
+	for _, e := range g.Edges() {
+		style := "solid"
+		if !e.Required {
+			style = "dashed"
+		}
+		fmt.Fprintf(&b, "  \"%s\" -> \"%s\" [style=%s];\n", dotEscape(e.From), dotEscape(e.To), style)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotEscape escapes s for use inside of a quoted DOT identifier
+func dotEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+// Write writes the graph in the given format, either "dot" or "json"
+func (g *Graph) Write(w io.Writer, format string) error {
+	switch format {
+	case "dot":
+		return g.WriteDOT(w)
+	case "json":
+		return g.WriteJSON(w)
+	default:
+		return fmt.Errorf("unknown graph format %q, expected one of [dot json]", format)
+	}
+}
+
+// Cycles returns the cycles in the graph, each as the path of services
+// that make up the cycle, starting and ending with the same service. A
+// cycle is reported once for every edge that closes it when walking the
+// graph depth first from the root, so not all elementary cycles are
+// necessarily returned.
+func (g *Graph) Cycles() [][]string {
+	adjacent := make(map[string][]string)
+	for _, e := range g.Edges() {
+		// Edges are sorted, so a required and an optional edge between
+		// the same services are next to each other
+		if to := adjacent[e.From]; len(to) > 0 && to[len(to)-1] == e.To {
+			continue
+		}
+		adjacent[e.From] = append(adjacent[e.From], e.To)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	stack := make([]string, 0)
+	cycles := make([][]string, 0)
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, to := range adjacent[name] {
+			switch state[to] {
+			case unvisited:
+				visit(to)
+			case visiting:
+				// to is on the stack, so the path from it to here is a cycle
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == to {
+						cycle := append(append([]string{}, stack[i:]...), to)
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+	}
+
+	// Start at the root to get paths relative to it, then visit the rest
+	// in case they're not reachable from it.
+	visit(g.Root)
+	for _, n := range g.Nodes() {
+		if state[n.Name] == unvisited {
+			visit(n.Name)
+		}
+	}
+
+	return cycles
+}
+
+// PathTo returns the shortest path of dependencies from the root to the
+// given service, including both, or nil when it's not in the graph
+func (g *Graph) PathTo(name string) []string {
+	adj := make(map[string][]string)
+	for _, e := range g.Edges() {
+		adj[e.From] = append(adj[e.From], e.To)
+	}
+
+	prev := map[string]string{g.Root: ""}
+	queue := []string{g.Root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == name {
+			path := make([]string, 0)
+			for n := name; n != ""; n = prev[n] {
+				path = append([]string{n}, path...)
+			}
+			return path
+		}
+
+		for _, to := range adj[cur] {
+			if _, ok := prev[to]; !ok {
+				prev[to] = cur
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package deps
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGraph() *Graph {
+	g := NewGraph("app", "devenv.yaml")
+	g.AddNode("a", "devenv.yaml")
+	g.AddNode("b", "service.yaml")
+	g.AddEdge("app", "b", false)
+	g.AddEdge("app", "a", true)
+	g.AddEdge("a", "b", true)
+	return g
+}
+
+func TestGraphServices(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, testGraph().Services())
+}
+
+func TestGraphDependenciesOf(t *testing.T) {
+	g := testGraph()
+	// Both a required and an optional dependency
+	g.AddEdge("app", "a", false)
+	assert.Equal(t, []string{"a", "b"}, g.DependenciesOf("app"))
+	assert.Equal(t, []string{"b"}, g.DependenciesOf("a"))
+	assert.Empty(t, g.DependenciesOf("b"))
+}
+
+func TestGraphWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, testGraph().Write(&buf, "dot"))
+	assert.Equal(t, `digraph dependencies {
+  rankdir=LR;
+  "a" [label="a\n(devenv.yaml)"];
+  "app" [label="app\n(devenv.yaml)", shape=box];
+  "b" [label="b\n(service.yaml)"];
+  "a" -> "b" [style=solid];
+  "app" -> "a" [style=solid];
+  "app" -> "b" [style=dashed];
+}
+`, buf.String())
+}
+
+func TestGraphWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, testGraph().Write(&buf, "json"))
+
+	var got struct {
+		Root  string  `json:"root"`
+		Nodes []*Node `json:"nodes"`
+		Edges []Edge  `json:"edges"`
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "app", got.Root)
+	assert.Equal(t, []*Node{
+		{Name: "a", Source: "devenv.yaml"},
+		{Name: "app", Source: "devenv.yaml"},
+		{Name: "b", Source: "service.yaml"},
+	}, got.Nodes)
+	assert.Equal(t, []Edge{
+		{From: "a", To: "b", Required: true},
+		{From: "app", To: "a", Required: true},
+		{From: "app", To: "b", Required: false},
+	}, got.Edges)
+}
+
+func TestGraphWriteUnknownFormat(t *testing.T) {
+	assert.Error(t, testGraph().Write(&bytes.Buffer{}, "svg"))
+}
+
+func TestGraphCycles(t *testing.T) {
+	g := NewGraph("app", "devenv.yaml")
+	for _, n := range []string{"a", "b", "c", "d"} {
+		g.AddNode(n, "devenv.yaml")
+	}
+	g.AddEdge("app", "a", true)
+	g.AddEdge("a", "b", true)
+	g.AddEdge("b", "c", true)
+	g.AddEdge("c", "a", true)
+	g.AddEdge("c", "a", false)
+	g.AddEdge("app", "d", false)
+	g.AddEdge("d", "app", true)
+
+	assert.Equal(t, [][]string{
+		{"a", "b", "c", "a"},
+		{"app", "d", "app"},
+	}, g.Cycles())
+	assert.Empty(t, testGraph().Cycles())
+}
@@ -0,0 +1,246 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the options used to configure dependency resolution.
+
+package deps
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/pkg/forge"
+	"github.com/getoutreach/gobox/pkg/box"
+	"github.com/pkg/errors"
+)
+
+// Options configures how the dependency tree of an application is resolved
+type Options struct {
+	// Sources are the names of the sources, in order of precedence, that the
+	// configuration of dependencies is read from. One of "forge" (or its
+	// old name, "github") or "local".
+	Sources []string
+
+	// Forge is the kind of forge the repositories of dependencies are
+	// hosted on, see forge.New. Defaults to forge.KindGitHub.
+	Forge string
+
+	// ForgeURL is the base URL of the forge, see forge.New
+	ForgeURL string
+
+	// ForgeOrg is the organization the repositories of dependencies are in.
+	// Defaults to the org configured in box for GitHub, and to the
+	// organization of the git origin of the current repository otherwise.
+	ForgeOrg string
+
+	// LocalSourceRoot is the directory the "local" source reads checkouts
+	// of repositories from. Defaults to ~/src.
+	LocalSourceRoot string
+
+	// Concurrency is the maximum number of services whose configuration is
+	// read at the same time
+	Concurrency int
+
+	// Cycles is how cyclical dependencies are handled
+	Cycles CyclePolicy
+
+	// Optional is which optional dependencies are included, when empty
+	// the policy configured in devenv.yaml is used
+	Optional config.OptionalPolicy
+
+	// MaxDepth is the maximum depth of the dependency tree, when 0 the
+	// maximum depth configured in devenv.yaml is used
+	MaxDepth int
+
+	// Cache denotes if configuration read from GitHub should be cached on disk
+	Cache bool
+
+	// CacheTTL is how long cached configuration is used without checking
+	// if the repository it was read from has changed
+	CacheTTL time.Duration
+
+	// Refresh ignores any cached configuration, re-reading (and caching)
+	// the configuration of every dependency
+	Refresh bool
+
+	// Profile is the name of the profile of devenv.yaml whose dependencies
+	// are resolved, when empty the top-level dependencies are
+	Profile string
+}
+
+// OptionsFromEnv returns Options configured through the environment:
+//
+//   - E2E_DEPENDENCY_SOURCES: comma separated list of Sources (default: "forge")
+//   - E2E_FORGE: Forge, one of "github", "github-enterprise", "gitlab" or "git" (default: "github")
+//   - E2E_FORGE_URL: ForgeURL
+//   - E2E_FORGE_ORG: ForgeOrg
+//   - E2E_LOCAL_SOURCE_ROOT: LocalSourceRoot (default: ~/src)
+//   - E2E_RESOLVE_CONCURRENCY: Concurrency (default: DefaultConcurrency)
+//   - E2E_DEPENDENCY_CYCLES: Cycles, one of "warn" or "fail" (default: "warn")
+//   - E2E_OPTIONAL_DEPS: Optional, one of "none", "root" or "all" (default: devenv.yaml)
+//   - E2E_MAX_DEPTH: MaxDepth (default: devenv.yaml)
+//   - E2E_DEPS_CACHE: set to "false" to disable Cache (default: "true")
+//   - E2E_DEPS_CACHE_TTL: CacheTTL as a duration, e.g. 30m (default: DefaultCacheTTL)
+//   - E2E_DEPS_CACHE_REFRESH: set to "true" to Refresh (default: "false")
+//   - E2E_PROFILE: Profile
+func OptionsFromEnv() (*Options, error) {
+	opts := &Options{
+		Sources:         []string{"forge"},
+		Forge:           os.Getenv("E2E_FORGE"),
+		ForgeURL:        os.Getenv("E2E_FORGE_URL"),
+		ForgeOrg:        os.Getenv("E2E_FORGE_ORG"),
+		LocalSourceRoot: os.Getenv("E2E_LOCAL_SOURCE_ROOT"),
+		Concurrency:     DefaultConcurrency,
+		Cycles:          CyclesWarn,
+		Optional:        config.OptionalPolicy(os.Getenv("E2E_OPTIONAL_DEPS")),
+		Cache:           os.Getenv("E2E_DEPS_CACHE") != "false",
+		CacheTTL:        DefaultCacheTTL,
+		Refresh:         os.Getenv("E2E_DEPS_CACHE_REFRESH") == "true",
+		Profile:         os.Getenv("E2E_PROFILE"),
+	}
+
+	if v := os.Getenv("E2E_DEPENDENCY_SOURCES"); v != "" {
+		opts.Sources = strings.Split(v, ",")
+	}
+
+	if v := os.Getenv("E2E_RESOLVE_CONCURRENCY"); v != "" {
+		var err error
+		if opts.Concurrency, err = strconv.Atoi(v); err != nil || opts.Concurrency < 1 {
+			return nil, fmt.Errorf("invalid E2E_RESOLVE_CONCURRENCY %q, expected a positive number", v)
+		}
+	}
+
+	switch v := CyclePolicy(os.Getenv("E2E_DEPENDENCY_CYCLES")); v {
+	case "":
+	case CyclesWarn, CyclesFail:
+		opts.Cycles = v
+	default:
+		return nil, fmt.Errorf("invalid E2E_DEPENDENCY_CYCLES %q, expected one of [warn fail]", v)
+	}
+
+	if err := opts.Optional.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid E2E_OPTIONAL_DEPS")
+	}
+
+	if v := os.Getenv("E2E_MAX_DEPTH"); v != "" {
+		var err error
+		if opts.MaxDepth, err = strconv.Atoi(v); err != nil || opts.MaxDepth < 0 {
+			return nil, fmt.Errorf("invalid E2E_MAX_DEPTH %q, expected a non-negative number", v)
+		}
+	}
+
+	if v := os.Getenv("E2E_DEPS_CACHE_TTL"); v != "" {
+		var err error
+		if opts.CacheTTL, err = time.ParseDuration(v); err != nil {
+			return nil, errors.Wrapf(err, "invalid E2E_DEPS_CACHE_TTL %q", v)
+		}
+	}
+
+	return opts, nil
+}
+
+// NewSources creates the configured sources, in order of precedence
+func (o *Options) NewSources(conf *box.Config) ([]Source, error) {
+	sources := make([]Source, 0, len(o.Sources))
+	for _, name := range o.Sources {
+		var s Source
+		var err error
+		switch strings.TrimSpace(name) {
+		case "forge", "github":
+			s, err = o.newForgeSource(conf)
+		case "local":
+			s, err = NewLocalSource(o.LocalSourceRoot, conf.Org)
+		default:
+			return nil, fmt.Errorf("unknown dependency source %q, expected one of [forge local]", name)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create %s dependency source", name)
+		}
+		sources = append(sources, s)
+	}
+
+	return sources, nil
+}
+
+// NewForge creates the provider of the configured forge, returning it
+// along with the organization the repositories of dependencies are in
+func (o *Options) NewForge(conf *box.Config) (forge.Provider, string, error) {
+	p, err := forge.New(o.Forge, o.ForgeURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, o.org(conf), nil
+}
+
+// CloneURL returns the URL the repository of the named dependency can be
+// cloned from. Unlike NewForge, no credentials of the forge are needed.
+func (o *Options) CloneURL(conf *box.Config, name string) (string, error) {
+	return forge.CloneURL(o.Forge, o.ForgeURL, o.org(conf)+"/"+name)
+}
+
+// org returns the organization the repositories of dependencies are in:
+// ForgeOrg, or the organization of the git origin when not on github.com,
+// or otherwise the one of box
+func (o *Options) org(conf *box.Config) string {
+	org := o.ForgeOrg
+	if org == "" && o.Forge != "" && o.Forge != forge.KindGitHub {
+		org = originOrg()
+	}
+	if org == "" {
+		org = conf.Org
+	}
+	return org
+}
+
+// newForgeSource creates a ForgeSource, wrapped in a CachedSource when
+// caching is enabled
+func (o *Options) newForgeSource(conf *box.Config) (Source, error) {
+	p, org, err := o.NewForge(conf)
+	if err != nil {
+		return nil, err
+	}
+	src := NewForgeSource(p, org)
+	if !o.Cache {
+		return src, nil
+	}
+
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewCachedSource(src, dir, org, o.CacheTTL, o.Refresh), nil
+}
+
+// originOrg returns the organization of the git origin of the current
+// repository, or an empty string if it can't be determined
+func originOrg() string {
+	out, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		return ""
+	}
+
+	r, err := forge.ParseRemote(strings.TrimSpace(string(out)))
+	if err != nil {
+		return ""
+	}
+	return r.Org()
+}
+
+// NewResolver creates a Resolver configured by the options
+func (o *Options) NewResolver(conf *box.Config) (*Resolver, error) {
+	sources, err := o.NewSources(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewResolver(sources...)
+	r.Concurrency = o.Concurrency
+	r.Cycles = o.Cycles
+	r.Optional = o.Optional
+	r.MaxDepth = o.MaxDepth
+	return r, nil
+}
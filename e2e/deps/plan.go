@@ -0,0 +1,109 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the deploy plan derived from the dependency graph.
+
+package deps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Plan is the order the dependencies of the root application are
+// deployed in
+type Plan struct {
+	// Waves are groups of services that can be deployed at the same time.
+	// Every service only requires services of earlier waves, so the first
+	// wave contains the leaves of the graph.
+	Waves [][]string `json:"waves"`
+
+	// Cyclic are the services that could not be ordered because they're
+	// part of, or require a service that's part of, a cycle. They're
+	// included in the last wave.
+	Cyclic []string `json:"cyclic,omitempty"`
+}
+
+// Plan computes the deploy plan of the dependencies of the root
+// application by ordering them topologically (leaves first) over their
+// required dependencies. The root itself is not part of the plan.
+func (g *Graph) Plan() *Plan {
+	// pending is the number of not yet planned required dependencies of a service
+	pending := make(map[string]int)
+	dependents := make(map[string][]string)
+	for _, name := range g.Services() {
+		pending[name] = 0
+	}
+	for _, e := range g.Edges() {
+		if !e.Required || e.From == g.Root || e.To == g.Root {
+			continue
+		}
+		pending[e.From]++
+		dependents[e.To] = append(dependents[e.To], e.From)
+	}
+
+	p := &Plan{Waves: make([][]string, 0)}
+	for len(pending) > 0 {
+		wave := make([]string, 0)
+		for name, n := range pending {
+			if n == 0 {
+				wave = append(wave, name)
+			}
+		}
+
+		// Everything left is blocked on a cycle, deploy it all at once
+		if len(wave) == 0 {
+			for name := range pending {
+				wave = append(wave, name)
+			}
+			sort.Strings(wave)
+			p.Cyclic = wave
+			p.Waves = append(p.Waves, wave)
+			break
+		}
+
+		sort.Strings(wave)
+		for _, name := range wave {
+			delete(pending, name)
+			for _, d := range dependents[name] {
+				pending[d]--
+			}
+		}
+		p.Waves = append(p.Waves, wave)
+	}
+
+	return p
+}
+
+// Order returns all services of the plan in deploy order
+func (p *Plan) Order() []string {
+	order := make([]string, 0)
+	for _, w := range p.Waves {
+		order = append(order, w...)
+	}
+	return order
+}
+
+// Write writes the plan in the given format, either "text" or "json"
+func (p *Plan) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		var b strings.Builder
+		for i, wave := range p.Waves {
+			fmt.Fprintf(&b, "wave %d: %s\n", i+1, strings.Join(wave, ", "))
+		}
+		if len(p.Cyclic) > 0 {
+			fmt.Fprintf(&b, "unordered (cyclical dependencies): %s\n", strings.Join(p.Cyclic, ", "))
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	default:
+		return fmt.Errorf("unknown plan format %q, expected one of [text json]", format)
+	}
+}
@@ -0,0 +1,47 @@
+package deps
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphPlan(t *testing.T) {
+	g := NewGraph("app", "devenv.yaml")
+	for _, n := range []string{"a", "b", "c", "d", "e"} {
+		g.AddNode(n, "devenv.yaml")
+	}
+	g.AddEdge("app", "a", true)
+	g.AddEdge("app", "e", false)
+	g.AddEdge("a", "b", true)
+	g.AddEdge("a", "c", true)
+	g.AddEdge("b", "c", true)
+	g.AddEdge("b", "d", true)
+	// Optional dependencies don't affect the order
+	g.AddEdge("d", "a", false)
+
+	p := g.Plan()
+	assert.Equal(t, [][]string{{"c", "d", "e"}, {"b"}, {"a"}}, p.Waves)
+	assert.Empty(t, p.Cyclic)
+	assert.Equal(t, []string{"c", "d", "e", "b", "a"}, p.Order())
+
+	var buf bytes.Buffer
+	assert.NoError(t, p.Write(&buf, "text"))
+	assert.Equal(t, "wave 1: c, d, e\nwave 2: b\nwave 3: a\n", buf.String())
+}
+
+func TestGraphPlanCycle(t *testing.T) {
+	g := NewGraph("app", "devenv.yaml")
+	for _, n := range []string{"a", "b", "c"} {
+		g.AddNode(n, "devenv.yaml")
+	}
+	g.AddEdge("app", "a", true)
+	g.AddEdge("a", "b", true)
+	g.AddEdge("b", "a", true)
+	g.AddEdge("b", "c", true)
+
+	p := g.Plan()
+	assert.Equal(t, [][]string{{"c"}, {"a", "b"}}, p.Waves)
+	assert.Equal(t, []string{"a", "b"}, p.Cyclic)
+}
@@ -0,0 +1,380 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic to resolve the dependency tree of an application.
+
+// Package deps contains logic for resolving the transitive dependencies
+// of an application declared in devenv.yaml (or legacy service.yaml).
+package deps
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/pkg/manifest"
+	"github.com/getoutreach/gobox/pkg/box"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// flagship is the name of the flagship
+const flagship = "flagship"
+
+// PossibleFiles are the files, in order of precedence, that a
+// dependency's own dependencies are read from
+var PossibleFiles = []string{"devenv.yaml", "noncompat-service.yaml", "service.yaml"}
+
+// DefaultConcurrency is the default number of services whose
+// configuration is read concurrently
+const DefaultConcurrency = 8
+
+// CyclePolicy determines how cyclical dependencies are handled
+type CyclePolicy string
+
+// Contains the possible cycle policies
+const (
+	// CyclesWarn logs a warning for every cyclical dependency
+	CyclesWarn CyclePolicy = "warn"
+
+	// CyclesFail fails resolution when there are cyclical dependencies
+	CyclesFail CyclePolicy = "fail"
+)
+
+// Resolver resolves the dependency tree of an application by reading the
+// configuration of each dependency from a list of sources
+type Resolver struct {
+	// Sources are tried in order, the first source containing any
+	// of PossibleFiles for a service is used
+	Sources []Source
+
+	// Concurrency is the maximum number of services whose configuration
+	// is read at the same time. Defaults to DefaultConcurrency.
+	Concurrency int
+
+	// Cycles is how cyclical dependencies are handled. Defaults to CyclesWarn.
+	Cycles CyclePolicy
+
+	// Optional is which optional dependencies are included. When empty,
+	// the policy configured in the root's devenv.yaml is used.
+	Optional config.OptionalPolicy
+
+	// MaxDepth is the maximum depth of the dependency tree. When 0, the
+	// maximum depth configured in the root's devenv.yaml is used.
+	MaxDepth int
+
+	// Aliases maps old names of services to their current name, on top of
+	// DefaultAliases
+	Aliases config.Aliases
+}
+
+// NewResolver creates a Resolver reading from the given sources, in order
+// of precedence
+func NewResolver(sources ...Source) *Resolver {
+	return &Resolver{Sources: sources, Concurrency: DefaultConcurrency, Cycles: CyclesWarn}
+}
+
+// ResolveCurrent builds the dependency graph of the application in the
+// current working directory from its devenv.yaml, configured by opts
+func ResolveCurrent(ctx context.Context, conf *box.Config, opts *Options) (*Graph, error) {
+	dc, err := config.FromFile("devenv.yaml")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse devenv.yaml")
+	}
+
+	return resolveRoot(ctx, conf, opts, "devenv.yaml", dc)
+}
+
+// ResolveRevision builds the dependency graph of the application in the
+// current working directory from its devenv.yaml at the given git
+// revision, configured by opts. A revision without a devenv.yaml has no
+// dependencies.
+func ResolveRevision(ctx context.Context, conf *box.Config, opts *Options, rev string) (*Graph, error) {
+	dc := &config.Devenv{}
+	//nolint:gosec // Why: We're OK with this, the revision is passed as a single argument.
+	if exec.CommandContext(ctx, "git", "cat-file", "-e", rev+":devenv.yaml").Run() == nil {
+		//nolint:gosec // Why: See above.
+		b, err := exec.CommandContext(ctx, "git", "show", rev+":devenv.yaml").Output()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read devenv.yaml at %s", rev)
+		}
+
+		if dc, err = config.Parse(b); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse devenv.yaml at %s", rev)
+		}
+	} else if err := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", rev+"^{commit}").Run(); err != nil {
+		return nil, fmt.Errorf("unknown git revision %q", rev)
+	}
+
+	return resolveRoot(ctx, conf, opts, "devenv.yaml@"+rev, dc)
+}
+
+// resolveRoot builds the dependency graph of the application in the
+// current working directory from its configuration, read from rootSource,
+// configured by opts
+func resolveRoot(ctx context.Context, conf *box.Config, opts *Options, rootSource string,
+	dc *config.Devenv) (*Graph, error) {
+	dc, err := dc.WithProfile(opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Profile != "" {
+		log.Info().Str("profile", opts.Profile).Msg("Using dependency profile")
+	}
+
+	r, err := opts.NewResolver(conf)
+	if err != nil {
+		return nil, err
+	}
+	r.Aliases = LoadAliases(dc)
+
+	return r.Resolve(ctx, currentAppName(), rootSource, dc)
+}
+
+// currentAppName returns the name of the application in the current
+// working directory, falling back to the name of the directory when
+// there is no service.yaml.
+func currentAppName() string {
+	if s, err := manifest.Load("."); err == nil && s.Name != "" {
+		return s.Name
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return filepath.Base(cwd)
+}
+
+// OptionalPolicy returns the policy of which optional dependencies of the
+// application configured by dc are resolved: the configured one, e.g. by
+// E2E_OPTIONAL_DEPS, or otherwise the one of its devenv.yaml, defaulting to
+// config.OptionalRoot
+func OptionalPolicy(configured config.OptionalPolicy, dc *config.Devenv) config.OptionalPolicy {
+	if configured != "" {
+		return configured
+	}
+	if dc.Resolution.OptionalDependencies != "" {
+		return dc.Resolution.OptionalDependencies
+	}
+	return config.OptionalRoot
+}
+
+// MaxDepth returns the maximum depth of the dependency tree of the
+// application configured by dc: the configured one, e.g. by E2E_MAX_DEPTH,
+// or otherwise the one of its devenv.yaml. 0 is no limit.
+func MaxDepth(configured int, dc *config.Devenv) int {
+	if configured != 0 {
+		return configured
+	}
+	return dc.Resolution.MaxDepth
+}
+
+// Resolve builds the dependency graph of the root application, whose
+// configuration (read from rootSource) is provided, by looking up the
+// dependencies of each dependency. The tree is walked breadth first, with
+// the services of each level being looked up concurrently. Deduplication
+// is done, and cyclical dependencies are only resolved once.
+func (r *Resolver) Resolve(ctx context.Context, root, rootSource string, dc *config.Devenv) (*Graph, error) {
+	optional := OptionalPolicy(r.Optional, dc)
+	if err := optional.Validate(); err != nil {
+		return nil, err
+	}
+
+	w := &walk{g: NewGraph(root, rootSource), maxDepth: MaxDepth(r.MaxDepth, dc), aliases: DefaultAliases.Merge(r.Aliases)}
+	w.g.aliases = w.aliases
+
+	frontier := make([]string, 0)
+	frontier = w.add(frontier, root, dc.Dependencies.Required, true, 1)
+	if optional != config.OptionalNone {
+		frontier = w.add(frontier, root, dc.Dependencies.Optional, false, 1)
+	}
+
+	for depth := 1; len(frontier) > 0; depth++ {
+		nodes := make([]*Node, len(frontier))
+		for i, serviceName := range frontier {
+			nodes[i] = w.g.Node(serviceName)
+		}
+
+		results, err := r.resolveAll(ctx, nodes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to grab dependencies")
+		}
+
+		next := make([]string, 0)
+		for i, n := range nodes {
+			n.Source, n.Origin = results[i].file, results[i].origin
+
+			next = w.add(next, n.Name, results[i].required, true, depth+1)
+			if optional == config.OptionalAll {
+				next = w.add(next, n.Name, results[i].optional, false, depth+1)
+			}
+		}
+		frontier = next
+	}
+
+	if err := r.checkCycles(w.g); err != nil {
+		return nil, err
+	}
+
+	return w.g, nil
+}
+
+// checkCycles reports the cycles in the graph according to r.Cycles
+func (r *Resolver) checkCycles(g *Graph) error {
+	cycles := g.Cycles()
+	if len(cycles) == 0 {
+		return nil
+	}
+
+	paths := make([]string, len(cycles))
+	for i, c := range cycles {
+		paths[i] = strings.Join(c, " -> ")
+	}
+
+	if r.Cycles == CyclesFail {
+		return fmt.Errorf("found %d cyclical dependencies: %s", len(paths), strings.Join(paths, ", "))
+	}
+
+	for _, p := range paths {
+		log.Warn().Str("cycle", p).Msg("Found cyclical dependency, services should not require each other")
+	}
+	return nil
+}
+
+// walk is the state of a breadth first walk of the dependency tree
+type walk struct {
+	// g is the graph being built
+	g *Graph
+
+	// maxDepth is the maximum depth of services added to the graph, 0
+	// meaning no limit
+	maxDepth int
+
+	// aliases maps old names of services to their current name
+	aliases config.Aliases
+}
+
+// add records that parent depends on deps, which are at the given depth,
+// returning frontier with the dependencies that have not been seen
+// before appended to it
+func (w *walk) add(frontier []string, parent string, deps []config.Dependency, required bool, depth int) []string {
+	for _, d := range deps {
+		// Aliases ensure we don't fail on deps that haven't updated their
+		// dependency on a renamed service yet.
+		if name, ok := w.aliases.Resolve(d.Name); ok {
+			log.Warn().Str("dep", d.Name).Str("alias-of", name).Str("parent", parent).
+				Msgf("Dependency uses a deprecated name, %s should depend on %s instead", parent, name)
+			d.Name = name
+		}
+
+		// Skip if we've already seen this dependency, this also prevents
+		// infinite resolution when we encounter a cyclical dependency.
+		// The graph is walked breadth first, so the ref closest to the root wins.
+		if n := w.g.Node(d.Name); n != nil {
+			w.g.AddEdge(parent, d.Name, required)
+			if d.Ref != n.Ref {
+				log.Warn().Str("dep", d.Name).Str("parent", parent).Str("ref", d.Ref).Str("using", n.Ref).
+					Msg("Dependency is pinned to conflicting refs, using the ref closest to the root")
+			}
+			continue
+		}
+
+		if w.maxDepth > 0 && depth > w.maxDepth {
+			log.Debug().Str("dep", d.Name).Str("parent", parent).Int("max-depth", w.maxDepth).
+				Msg("Skipping dependency deeper than the maximum depth")
+			continue
+		}
+
+		w.g.AddEdge(parent, d.Name, required)
+		n := w.g.AddNode(d.Name, "")
+		n.Ref, n.ProvisionTarget, n.Readiness = d.Ref, d.ProvisionTarget, d.Readiness
+		frontier = append(frontier, d.Name)
+	}
+	return frontier
+}
+
+// repoDependencies are the dependencies found in the repository of a service
+type repoDependencies struct {
+	// required are the required dependencies of the service
+	required []config.Dependency
+
+	// optional are the optional dependencies of the service
+	optional []config.Dependency
+
+	// origin is the name of the source the dependencies were read from
+	origin string
+
+	// file is the file the dependencies were read from
+	file string
+}
+
+// resolveAll finds the dependencies of all of the provided services, with
+// at most r.Concurrency lookups running at the same time. The returned
+// results are in the same order as the services.
+func (r *Resolver) resolveAll(ctx context.Context, services []*Node) ([]repoDependencies, error) {
+	concurrency := r.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+
+	results := make([]repoDependencies, len(services))
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(concurrency)
+	for i, n := range services {
+		i, n := i, n
+		eg.Go(func() error {
+			log.Info().Str("dep", n.Name).Str("ref", n.Ref).Msg("Resolving dependency")
+
+			var err error
+			results[i], err = r.findDependenciesInRepo(ctx, n.Name, n.Ref)
+			return err
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// findDependenciesInRepo finds the dependencies in a repository, at the
+// given ref, at all of the possible paths. An error is only returned when
+// ctx is canceled.
+func (r *Resolver) findDependenciesInRepo(ctx context.Context, serviceName, ref string) (repoDependencies, error) {
+	l := log.With().Str("service", serviceName).Str("ref", ref).Logger()
+	for _, s := range r.Sources {
+		for _, f := range PossibleFiles {
+			b, err := s.ReadFile(ctx, serviceName, f, ref)
+			if ctx.Err() != nil {
+				return repoDependencies{}, ctx.Err()
+			}
+			if err != nil {
+				if !errors.Is(err, ErrNotFound) {
+					l.Warn().Err(err).Str("source", s.Name()).Str("file", f).Msg("Unable to read file")
+				}
+				continue // we continue to the next file
+			}
+
+			dc, err := config.Parse(b)
+			if err != nil {
+				l.Warn().Err(err).Str("source", s.Name()).Str("file", f).Msg("Unable to parse config file")
+				continue
+			}
+
+			return repoDependencies{
+				required: dc.Dependencies.Required,
+				optional: dc.Dependencies.Optional,
+				origin:   s.Name(),
+				file:     f,
+			}, nil
+		}
+	}
+
+	l.Warn().Msgf("Failed to find any of the following %v, will not try to calculate dependencies of this service", PossibleFiles)
+	return repoDependencies{}, nil
+}
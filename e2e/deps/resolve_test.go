@@ -0,0 +1,263 @@
+package deps
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// memorySource is a Source backed by a map of service[@ref] -> file -> contents
+type memorySource struct {
+	name  string
+	files map[string]map[string]string
+}
+
+func (s *memorySource) Name() string { return s.name }
+
+func (s *memorySource) ReadFile(_ context.Context, serviceName, file, ref string) ([]byte, error) {
+	key := serviceName
+	if ref != "" {
+		key += "@" + ref
+	}
+	contents, ok := s.files[key][file]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return []byte(contents), nil
+}
+
+func rootConfig(t *testing.T, contents string) *config.Devenv {
+	dc, err := config.Parse([]byte(contents))
+	assert.NoError(t, err)
+	return dc
+}
+
+func TestResolve(t *testing.T) {
+	src := &memorySource{name: "memory", files: map[string]map[string]string{
+		"a": {"devenv.yaml": "dependencies:\n  required: [b]\n  optional: [c]\n"},
+		"b": {"service.yaml": "dependencies:\n  required: [a, flagship]\n"},
+	}}
+
+	g, err := NewResolver(src).Resolve(context.Background(), "app", "devenv.yaml",
+		rootConfig(t, "dependencies:\n  required: [a]\n  optional: [d]\n"))
+	assert.NoError(t, err)
+
+	// c is an optional dependency of a transitive dependency, so it is not followed
+	assert.Equal(t, []string{"a", "b", "d", "outreach"}, g.Services())
+	assert.Equal(t, &Node{Name: "a", Source: "devenv.yaml", Origin: "memory"}, g.Node("a"))
+	assert.Equal(t, &Node{Name: "b", Source: "service.yaml", Origin: "memory"}, g.Node("b"))
+	assert.Equal(t, &Node{Name: "d"}, g.Node("d"))
+	assert.Equal(t, []Edge{
+		{From: "a", To: "b", Required: true},
+		{From: "app", To: "a", Required: true},
+		{From: "app", To: "d", Required: false},
+		{From: "b", To: "a", Required: true},
+		{From: "b", To: "outreach", Required: true},
+	}, g.Edges())
+}
+
+func TestResolveSourcePrecedence(t *testing.T) {
+	local := &memorySource{name: "local", files: map[string]map[string]string{
+		"a": {"service.yaml": "dependencies:\n  required: [c]\n"},
+	}}
+	remote := &memorySource{name: "github", files: map[string]map[string]string{
+		"a": {"devenv.yaml": "dependencies:\n  required: [b]\n"},
+	}}
+
+	g, err := NewResolver(local, remote).Resolve(context.Background(), "app", "devenv.yaml",
+		rootConfig(t, "dependencies:\n  required: [a]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, g.Services())
+	assert.Equal(t, &Node{Name: "a", Source: "service.yaml", Origin: "local"}, g.Node("a"))
+}
+
+func TestLocalSource(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "getoutreach", "a"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "getoutreach", "a", "devenv.yaml"), []byte("service: true"), 0o600))
+
+	s, err := NewLocalSource(root, "getoutreach")
+	assert.NoError(t, err)
+
+	b, err := s.ReadFile(context.Background(), "a", "devenv.yaml", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "service: true", string(b))
+
+	_, err = s.ReadFile(context.Background(), "a", "service.yaml", "")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// countingSource wraps a Source and records the maximum number of
+// concurrent reads
+type countingSource struct {
+	Source
+
+	mu       sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (s *countingSource) ReadFile(ctx context.Context, serviceName, file, ref string) ([]byte, error) {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.max {
+		s.max = s.inFlight
+	}
+	s.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+	return s.Source.ReadFile(ctx, serviceName, file, ref)
+}
+
+func TestResolveConcurrencyLimit(t *testing.T) {
+	files := map[string]map[string]string{}
+	rootDeps := make([]string, 0)
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("svc-%02d", i)
+		rootDeps = append(rootDeps, name)
+		files[name] = map[string]string{"devenv.yaml": "dependencies:\n  required: [shared]\n"}
+	}
+	src := &countingSource{Source: &memorySource{name: "memory", files: files}}
+
+	r := NewResolver(src)
+	r.Concurrency = 3
+	g, err := r.Resolve(context.Background(), "app", "devenv.yaml",
+		rootConfig(t, "dependencies:\n  required: ["+strings.Join(rootDeps, ", ")+"]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, append([]string{"shared"}, rootDeps...), g.Services())
+	assert.LessOrEqual(t, src.max, 3)
+}
+
+func TestResolveCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	src := &memorySource{name: "memory", files: map[string]map[string]string{}}
+	_, err := NewResolver(src).Resolve(ctx, "app", "devenv.yaml", rootConfig(t, "dependencies:\n  required: [a]\n"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestResolveCyclesFail(t *testing.T) {
+	src := &memorySource{name: "memory", files: map[string]map[string]string{
+		"a": {"devenv.yaml": "dependencies:\n  required: [b]\n"},
+		"b": {"devenv.yaml": "dependencies:\n  required: [a]\n"},
+	}}
+
+	r := NewResolver(src)
+	r.Cycles = CyclesFail
+	_, err := r.Resolve(context.Background(), "app", "devenv.yaml", rootConfig(t, "dependencies:\n  required: [a]\n"))
+	assert.EqualError(t, err, "found 1 cyclical dependencies: a -> b -> a")
+}
+
+func TestResolvePinned(t *testing.T) {
+	src := &memorySource{name: "memory", files: map[string]map[string]string{
+		"a":        {"devenv.yaml": "dependencies:\n  required: [c]\n"},
+		"a@v1.2.0": {"devenv.yaml": "dependencies:\n  required: [b@feature/x]\n"},
+		"b":        {"devenv.yaml": "dependencies:\n  required: [c]\n"},
+		"b@feature/x": {"devenv.yaml": "dependencies:\n  required:\n" +
+			"    - name: d\n      ref: 0123abc\n    - a@v2.0.0\n"},
+	}}
+
+	g, err := NewResolver(src).Resolve(context.Background(), "app", "devenv.yaml",
+		rootConfig(t, "dependencies:\n  required: [a@v1.2.0]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "d"}, g.Services())
+	assert.Equal(t, []*Node{
+		{Name: "a", Ref: "v1.2.0", Source: "devenv.yaml", Origin: "memory"},
+		{Name: "b", Ref: "feature/x", Source: "devenv.yaml", Origin: "memory"},
+		{Name: "d", Ref: "0123abc"},
+	}, g.Pinned())
+}
+
+func TestResolveV2Metadata(t *testing.T) {
+	src := &memorySource{name: "memory", files: map[string]map[string]string{
+		"a": {"devenv.yaml": "apiVersion: v2\ndependencies:\n  - name: b\n    provisionTarget: flagship\n" +
+			"  - name: c\n    optional: true\n"},
+	}}
+
+	g, err := NewResolver(src).Resolve(context.Background(), "app", "devenv.yaml",
+		rootConfig(t, "apiVersion: v2\ndependencies:\n  - name: a\n    readiness:\n      timeout: 5m\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, g.Services())
+	assert.Equal(t, &config.Readiness{Timeout: "5m"}, g.Node("a").Readiness)
+	target, _ := g.ProvisionTarget(config.ProvisionTargets{Rules: []config.ProvisionTargetRule{{Dependency: "x", Target: "x"}}})
+	assert.Equal(t, "flagship", target)
+}
+
+func TestResolveAliases(t *testing.T) {
+	src := &memorySource{name: "memory", files: map[string]map[string]string{
+		"a": {"devenv.yaml": "dependencies:\n  required: [old-b, flagship]\n"},
+	}}
+
+	r := NewResolver(src)
+	r.Aliases = config.Aliases{"old-b": "b"}
+	g, err := r.Resolve(context.Background(), "app", "devenv.yaml", rootConfig(t, "dependencies:\n  required: [a, b]\n"))
+	assert.NoError(t, err)
+	// Configured aliases are put on top of the default aliases
+	assert.Equal(t, []string{"a", "b", "outreach"}, g.Services())
+	assert.Contains(t, g.Edges(), Edge{From: "a", To: "b", Required: true})
+	assert.Contains(t, g.Edges(), Edge{From: "a", To: "outreach", Required: true})
+
+	// A default alias configured to an empty name is disabled
+	r.Aliases = config.Aliases{flagship: ""}
+	g, err = r.Resolve(context.Background(), "app", "devenv.yaml", rootConfig(t, "dependencies:\n  required: [a]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "flagship", "old-b"}, g.Services())
+}
+
+func TestResolveOptionalPolicyAndMaxDepth(t *testing.T) {
+	src := &memorySource{name: "memory", files: map[string]map[string]string{
+		"a": {"devenv.yaml": "dependencies:\n  required: [b]\n  optional: [c]\n"},
+		"b": {"devenv.yaml": "dependencies:\n  required: [d]\n"},
+		"c": {"devenv.yaml": "dependencies:\n  required: [e]\n"},
+	}}
+	root := "dependencies:\n  required: [a]\n  optional: [o]\n"
+
+	tests := []struct {
+		name     string
+		optional config.OptionalPolicy
+		maxDepth int
+		devenv   string
+		want     []string
+	}{
+		{name: "default", want: []string{"a", "b", "d", "o"}},
+		{name: "none", optional: config.OptionalNone, want: []string{"a", "b", "d"}},
+		{name: "all", optional: config.OptionalAll, want: []string{"a", "b", "c", "d", "e", "o"}},
+		{name: "max depth", maxDepth: 2, want: []string{"a", "b", "o"}},
+		{
+			name:   "devenv.yaml",
+			devenv: "resolution:\n  optionalDependencies: all\n  maxDepth: 2\n",
+			want:   []string{"a", "b", "c", "o"},
+		},
+		{
+			name:     "overrides devenv.yaml",
+			optional: config.OptionalNone,
+			maxDepth: 1,
+			devenv:   "resolution:\n  optionalDependencies: all\n  maxDepth: 2\n",
+			want:     []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewResolver(src)
+			r.Optional = tt.optional
+			r.MaxDepth = tt.maxDepth
+
+			g, err := r.Resolve(context.Background(), "app", "devenv.yaml", rootConfig(t, root+tt.devenv))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, g.Services())
+		})
+	}
+}
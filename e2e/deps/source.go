@@ -0,0 +1,145 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the sources dependency configuration is read from.
+
+package deps
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/getoutreach/devbase/v2/pkg/forge"
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by a Source when the requested service or
+// file does not exist in it
+var ErrNotFound = errors.New("not found")
+
+// Source reads configuration files from the repositories of services
+type Source interface {
+	// Name returns a short, human readable, name of the source, e.g. "github"
+	Name() string
+
+	// ReadFile returns the contents of file in the repository of the given
+	// service at the given git ref, or the default branch when ref is empty.
+	// ErrNotFound is returned if the service, ref or file doesn't exist.
+	ReadFile(ctx context.Context, serviceName, file, ref string) ([]byte, error)
+}
+
+// Revisioner is implemented by sources that can cheaply determine the
+// current revision of the repository of a service
+type Revisioner interface {
+	// Revision returns the current revision, e.g. commit SHA, of the
+	// given ref (or the default branch when empty) of the repository of
+	// the given service. lastKnown is the last revision observed by the
+	// caller, if any, which may be used to avoid transferring data when the
+	// revision hasn't changed.
+	Revision(ctx context.Context, serviceName, ref, lastKnown string) (string, error)
+}
+
+// ForgeSource reads files from repositories of an organization on a
+// forge, e.g. GitHub or GitLab
+type ForgeSource struct {
+	p   forge.Provider
+	org string
+}
+
+// NewForgeSource creates a ForgeSource reading the repositories of the
+// given organization, e.g. "getoutreach" or for GitLab "group/subgroup",
+// from a provider
+func NewForgeSource(p forge.Provider, org string) *ForgeSource {
+	return &ForgeSource{p: p, org: org}
+}
+
+// Name implements Source
+func (s *ForgeSource) Name() string {
+	return s.p.Name()
+}
+
+// ReadFile implements Source
+func (s *ForgeSource) ReadFile(ctx context.Context, serviceName, file, ref string) ([]byte, error) {
+	b, err := s.p.ReadFile(ctx, s.org+"/"+serviceName, file, ref)
+	if errors.Is(err, forge.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s of %s", file, serviceName)
+	}
+	return b, nil
+}
+
+// Revision implements Revisioner when the provider can determine the
+// revision of a repository, e.g. the commit SHA a ref points to
+func (s *ForgeSource) Revision(ctx context.Context, serviceName, ref, lastKnown string) (string, error) {
+	r, ok := s.p.(interface {
+		Revision(ctx context.Context, repo, ref, lastKnown string) (string, error)
+	})
+	if !ok {
+		return "", fmt.Errorf("%s can't determine revisions", s.p.Name())
+	}
+	return r.Revision(ctx, s.org+"/"+serviceName, ref, lastKnown)
+}
+
+// LocalSource reads files from local checkouts of repositories, laid
+// out as <root>/<org>/<service>. Files are read from the working tree,
+// unless a ref is requested in which case they're read from git.
+type LocalSource struct {
+	root string
+	org  string
+}
+
+// DefaultLocalSourceRoot returns the directory a LocalSource reads
+// checkouts from when no root is configured, ~/src
+func DefaultLocalSourceRoot() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to determine home directory")
+	}
+	return filepath.Join(homeDir, "src"), nil
+}
+
+// NewLocalSource creates a LocalSource reading checkouts of repositories of
+// the given org from root. If root is empty DefaultLocalSourceRoot is used.
+func NewLocalSource(root, org string) (*LocalSource, error) {
+	if root == "" {
+		var err error
+		if root, err = DefaultLocalSourceRoot(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &LocalSource{root: root, org: org}, nil
+}
+
+// Name implements Source
+func (*LocalSource) Name() string {
+	return "local"
+}
+
+// ReadFile implements Source
+func (s *LocalSource) ReadFile(ctx context.Context, serviceName, file, ref string) ([]byte, error) {
+	dir := filepath.Join(s.root, s.org, serviceName)
+	if ref == "" {
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return b, err
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		return nil, ErrNotFound
+	}
+
+	//nolint:gosec // Why: refs are validated, see config.ValidateRef, and can't be an option after --end-of-options.
+	b, err := exec.CommandContext(ctx, "git", "-C", dir, "show", "--end-of-options", ref+":"+file).Output()
+	if err != nil {
+		// git doesn't distinguish between a missing ref and a missing file
+		return nil, ErrNotFound
+	}
+	return b, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the selection of the devenv provision target.
+
+package deps
+
+import (
+	"github.com/getoutreach/devbase/v2/e2e/config"
+)
+
+// DefaultProvisionTarget is the provision target used when no rule matches
+// and no default is configured
+const DefaultProvisionTarget = "base"
+
+// DefaultProvisionTargetRules are the rules used when no rules are
+// configured in devenv.yaml or box
+var DefaultProvisionTargetRules = []config.ProvisionTargetRule{{Dependency: "outreach", Target: flagship}}
+
+// ProvisionTarget selects the devenv provision target for the dependencies
+// in the graph. The rules of all of the configurations are considered, in
+// order, followed by the provision targets dependencies declared they
+// need. Rules match on the dependency, or on an alias of it, as the graph
+// only contains current names. The matching rule with the highest
+// priority wins, it is returned along with the target. When no rule
+// matches the first configured default, or DefaultProvisionTarget, is
+// used and the rule is nil.
+func (g *Graph) ProvisionTarget(confs ...config.ProvisionTargets) (string, *config.ProvisionTargetRule) {
+	rules := make([]config.ProvisionTargetRule, 0)
+	def := ""
+	for _, c := range confs {
+		rules = append(rules, c.Rules...)
+		if def == "" {
+			def = c.Default
+		}
+	}
+	if len(rules) == 0 {
+		rules = append(rules, DefaultProvisionTargetRules...)
+	}
+	for _, n := range g.Nodes() {
+		if n.ProvisionTarget != "" && n.Name != g.Root {
+			rules = append(rules, config.ProvisionTargetRule{Dependency: n.Name, Target: n.ProvisionTarget})
+		}
+	}
+
+	var match *config.ProvisionTargetRule
+	for i := range rules {
+		r := &rules[i]
+		dep, _ := g.aliases.Resolve(r.Dependency)
+		if r.Target == "" || dep == g.Root || !g.Has(dep) {
+			continue
+		}
+		if match == nil || r.Priority > match.Priority {
+			match = r
+		}
+	}
+
+	if match != nil {
+		return match.Target, match
+	}
+	if def == "" {
+		def = DefaultProvisionTarget
+	}
+	return def, nil
+}
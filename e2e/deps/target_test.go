@@ -0,0 +1,83 @@
+package deps
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphProvisionTarget(t *testing.T) {
+	g := NewGraph("app", "devenv.yaml")
+	g.AddNode("outreach", "devenv.yaml")
+	g.AddNode("a", "devenv.yaml")
+	g.AddNode("b", "devenv.yaml").ProvisionTarget = "hinted"
+	g.AddEdge("app", "outreach", true)
+	g.AddEdge("app", "a", true)
+	g.AddEdge("a", "b", true)
+
+	tests := []struct {
+		name   string
+		confs  []config.ProvisionTargets
+		target string
+	}{
+		{
+			name:   "default rules",
+			target: flagship,
+		},
+		{
+			name: "configured rules replace the default rules",
+			confs: []config.ProvisionTargets{
+				{Rules: []config.ProvisionTargetRule{{Dependency: "a", Target: "a-target"}}},
+			},
+			target: "a-target",
+		},
+		{
+			name: "priority wins over order",
+			confs: []config.ProvisionTargets{
+				{Rules: []config.ProvisionTargetRule{{Dependency: "a", Target: "a-target"}}},
+				{Rules: []config.ProvisionTargetRule{{Dependency: "outreach", Target: "big", Priority: 10}}},
+			},
+			target: "big",
+		},
+		{
+			name: "dependency metadata",
+			confs: []config.ProvisionTargets{
+				{Rules: []config.ProvisionTargetRule{{Dependency: "missing", Target: "other"}}},
+			},
+			target: "hinted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, rule := g.ProvisionTarget(tt.confs...)
+			assert.Equal(t, tt.target, target)
+			assert.NotNil(t, rule)
+		})
+	}
+
+	empty := NewGraph("app", "devenv.yaml")
+	target, rule := empty.ProvisionTarget(config.ProvisionTargets{Default: "small"}, config.ProvisionTargets{Default: "box"})
+	assert.Equal(t, "small", target)
+	assert.Nil(t, rule)
+
+	target, _ = empty.ProvisionTarget()
+	assert.Equal(t, DefaultProvisionTarget, target)
+}
+
+func TestGraphProvisionTargetAliases(t *testing.T) {
+	r := NewResolver(&memorySource{name: "memory"})
+	r.Aliases = config.Aliases{"old-mint": "mint"}
+	g, err := r.Resolve(context.Background(), "app", "devenv.yaml", rootConfig(t, "dependencies:\n  required: [old-mint]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mint"}, g.Services())
+
+	// A rule written for the old name matches the renamed dependency
+	target, rule := g.ProvisionTarget(config.ProvisionTargets{
+		Rules: []config.ProvisionTargetRule{{Dependency: "old-mint", Target: "mint-target"}},
+	})
+	assert.Equal(t, "mint-target", target)
+	assert.Equal(t, "old-mint", rule.Dependency)
+}
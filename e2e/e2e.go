@@ -2,32 +2,30 @@
 
 // Description: This is the entrypoint of the e2e runner for the devenv.
 
-// TODO(george-e-shaw-iv): Remove all calls to log.Fatal with graceful exits in mind.
-
 package main
 
 import (
 	"context"
 	"encoding/xml"
+	"flag"
 	"fmt"
 	"go/build"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
-	"sync"
+	"syscall"
 
 	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/e2e/deps"
+	"github.com/getoutreach/devbase/v2/pkg/tracing"
 	"github.com/getoutreach/gobox/pkg/box"
-	githubauth "github.com/getoutreach/gobox/pkg/cli/github"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// flagship is the name of the flagship
-const flagship = "flagship"
-
 // junitTestResultPath path to test results after we run (devenv apps e2e)
 const junitTestResultPath = "./bin/unit-tests.xml"
 
@@ -35,134 +33,17 @@ const junitTestResultPath = "./bin/unit-tests.xml"
 const devenvAlreadyExists = "Re-using existing cluster, this may lead to a non-reproducible failure/success. " +
 	"To ensure a clean operation, run `devenv destroy` before running tests"
 
-// osStdInOutErr is a helper function to use the os stdin/out/err
-func osStdInOutErr(c *exec.Cmd) *exec.Cmd {
-	c.Stdin = os.Stdin
-	return osStdOutErr(c)
-}
-
-// osStdOutErr is a helper function to use the os stdout/err
-func osStdOutErr(c *exec.Cmd) *exec.Cmd {
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	return c
-}
-
-// BuildDependenciesList builds a list of dependencies by cloning them
-// and appending them to the list. Deduplication is done and returned
-// is a flat list of all of the dependencies of the initial root
-// application who's dependency list was provided.
-func BuildDependenciesList(ctx context.Context, conf *box.Config) ([]string, error) {
-	deps := make(map[string]struct{})
-
-	dc, err := config.FromFile("devenv.yaml")
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse devenv.yaml")
-	}
-
-	for _, d := range dc.GetAllDependencies() {
-		if err := grabDependencies(ctx, conf, deps, d); err != nil {
-			return nil, err
-		}
-	}
-
-	depsList := make([]string, 0)
-	for d := range deps {
-		depsList = append(depsList, d)
-	}
-
-	return depsList, nil
-}
-
-// findDependenciesInRepo finds the dependencies in a repository
-// at all of the possible paths
-func findDependenciesInRepo(ctx context.Context, conf *box.Config, serviceName string) (map[string]struct{}, error) {
-	possibleFiles := []string{"devenv.yaml", "noncompat-service.yaml", "service.yaml"}
-	gh, err := githubauth.NewClient()
-	if err != nil {
-		return nil, err
-	}
-
-	var dc *config.Devenv
-	for _, f := range possibleFiles {
-		dc, err = config.FromGitHub(ctx, conf, serviceName, gh, f)
-		if err != nil {
-			continue // we continue to the next file, err is logged in getConfig
-		}
-
-		// We found a file, stop looking
-		break
-	}
-
-	if dc == nil {
-		log.Warn().Str("service", serviceName).
-			Msgf("Failed to find any of the following %v, will not try to calculate dependencies of this service", possibleFiles)
-		return nil, nil
-	}
-
-	deps := make(map[string]struct{})
-	// We deploy just required transitive dependencies
-	for _, d := range dc.Dependencies.Required {
-		deps[d] = struct{}{}
-	}
-
-	return deps, nil
-}
-
-// grabDependencies traverses the dependency tree by calculating
-// it on the fly via git cloning of the dependencies. Passed in
-// is a hash map used to prevent infinite recursion and de-duplicate
-// dependencies. New dependencies are inserted into the provided hash-map
-func grabDependencies(ctx context.Context, conf *box.Config, deps map[string]struct{}, serviceName string) error {
-	// We special case this here to ensure we don't fail on deps that haven't updated
-	// their dependency yet.
-	if serviceName == flagship {
-		serviceName = "outreach"
-	}
-
-	// Skip if we've already been downloaded
-	if _, ok := deps[serviceName]; ok {
-		return nil
-	}
-
-	log.Info().Str("dep", serviceName).Msg("Resolving dependency")
-
-	// Find the dependencies of this repo
-	foundDeps, err := findDependenciesInRepo(ctx, conf, serviceName)
-
-	if err != nil {
-		return errors.Wrap(err, "failed to grab dependencies")
-	}
-
-	// Mark us as resolved to prevent inf dependency resolution
-	// when we encounter cyclical dependency.
-	deps[serviceName] = struct{}{}
-
-	for d := range foundDeps {
-		if err := grabDependencies(ctx, conf, deps, d); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 // provisionNew destroys and re-provisions a devenv
-func provisionNew(ctx context.Context, target string) error { // nolint:unparam // Why: keeping in the interface for now
+func provisionNew(ctx context.Context, ex executor, target string) error {
 	//nolint:errcheck // Why: Best effort remove existing cluster
-	exec.CommandContext(ctx, "devenv", "--skip-update", "destroy").Run()
-
-	if err := osStdInOutErr(exec.CommandContext(ctx, "devenv", "--skip-update",
-		"provision", "--snapshot-target", target)).Run(); err != nil {
-		log.Fatal().Err(err).Msg("Failed to provision devenv")
-	}
+	ex.Run(ctx, newCommand("devenv", "--skip-update", "destroy"))
 
-	return nil
+	return ex.Run(ctx, stdInOutErr("devenv", "--skip-update", "provision", "--snapshot-target", target))
 }
 
 // runDevconfig executes devconfig command
-func runDevconfig(ctx context.Context) error {
-	out, err := exec.CommandContext(ctx, "./scripts/shell-wrapper.sh", "devconfig.sh").CombinedOutput()
+func runDevconfig(ctx context.Context, ex executor) error {
+	out, err := ex.Output(ctx, newCommand("./scripts/shell-wrapper.sh", "devconfig.sh"))
 	if err != nil {
 		return fmt.Errorf("%s", out)
 	}
@@ -223,71 +104,14 @@ func shouldRunE2ETests() (bool, error) {
 	return runEndToEndTests, err
 }
 
-// runE2ETestsUsingDevspace uses devspace and binary sync to deploy application. There's no devconfig and docker build.
-func runE2ETestsUsingDevspace(ctx context.Context, conf *box.Config) error {
-	if isDevenvProvisioned(ctx) {
-		log.Info().Msgf(devenvAlreadyExists)
-	} else {
-		err := provisionDevenv(ctx, conf)
-		if err != nil {
-			return err
-		}
-	}
-
-	serviceName, err := config.ReadServiceName()
-	if err != nil {
-		return err
-	}
-
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		log.Info().Msg("Building binaries for devspace pod")
-		if err := osStdInOutErr(exec.CommandContext(ctx, "make", "devspace")).Run(); err != nil {
-			log.Error().Err(err).Msg("Error when building for devspace")
-			panic(err)
-		}
-	}()
-
-	log.Info().Msgf("Deploying latest stable version of %s application into cluster together with dependencies", serviceName)
-	if err := osStdInOutErr(exec.CommandContext(
-		ctx, "devenv", "--skip-update", "apps", "deploy", "--with-deps", serviceName)).Run(); err != nil {
-		return errors.Wrapf(err, "Failed to deploy %s into devenv", serviceName)
-	}
-
-	wg.Wait()
-
-	log.Info().Msg("Starting devspace pod and running e2e tests")
-	if err := osStdInOutErr(exec.CommandContext(ctx, "devenv", "--skip-update", "apps", "e2e", "--sync-binaries", ".")).Run(); err != nil {
-		return errors.Wrapf(err, "Failed to deploy %s into devenv", serviceName)
-	}
-	if runningInCi() {
-		// Copy junit report to place where CircleCi expects it
-		if err := osStdInOutErr(exec.CommandContext(ctx, "cp", junitTestResultPath, "/tmp/test-results/")).Run(); err != nil {
-			return errors.Wrap(err, "Unable to copy tests results to CircleCI artifact path")
-		}
-	}
-	testsSuccess, err := parseResultFromJunitReport()
-	if err != nil {
-		return err
-	}
-	if !testsSuccess {
-		return errors.New("E2E Tests failed")
-	}
-	log.Info().Msg("E2E Tests succeeded.")
-	return nil
-}
-
 // parseResultFromJunitReport parses if tests succeeded from junit xml file
-func parseResultFromJunitReport() (bool, error) {
+func parseResultFromJunitReport(readFile fileReader) (bool, error) {
 	type Testsuite struct {
 		XMLName  xml.Name `xml:"testsuites"`
 		Failures int      `xml:"failures,attr"`
 	}
 
-	data, err := os.ReadFile(junitTestResultPath)
+	data, err := readFile(junitTestResultPath)
 	if err != nil {
 		return false, errors.Wrap(err, "Unable to find e2e tests results")
 	}
@@ -301,17 +125,67 @@ func parseResultFromJunitReport() (bool, error) {
 	return testsuite.Failures == 0, nil
 }
 
-func main() { //nolint:funlen,gocyclo // Why: there are no reusable parts to extract
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func main() {
+	os.Exit(run())
+}
+
+// run runs the e2e runner, returning its exit code. Cleanup, e.g. of the
+// devenv tunnel, is always done before returning.
+func run() (code int) {
+	env, err := config.RunnerFromEnv()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+		return exitSetup
+	}
+
+	depsGraph := flag.String("deps-graph", "",
+		"print the resolved dependency tree in the given format (dot or json) and exit")
+	refresh := flag.Bool("refresh", false, "ignore cached dependency configuration and re-read it from the forge")
+	optionalDeps := flag.String("optional-deps", "",
+		"which optional dependencies to include: none, root or all (default: devenv.yaml, or root)")
+	maxDepth := flag.Int("max-depth", 0, "maximum depth of the dependency tree (default: devenv.yaml, or no limit)")
+	deployWaves := flag.Bool("deploy-waves", env.DeployWaves,
+		"deploy dependencies in topologically ordered waves, waiting for each wave to become ready")
+	profile := flag.String("profile", os.Getenv("E2E_PROFILE"),
+		"name of the devenv.yaml profile whose dependencies are deployed (default: the top-level dependencies)")
+	plan := flag.Bool("plan", false, "print what the run would do, without executing any command, and exit")
+	planFormat := flag.String("plan-format", "text", "format of --plan: text or json")
+	teardown := flag.String("teardown", env.Teardown,
+		"when to destroy the devenv once the run is done: always, on-success or never "+
+			"(default: on-success for a devenv the run provisioned outside of CI, otherwise never)")
+	from := flag.String("from", "", "resume the recorded run from the given stage, skipping the stages before it")
+	only := flag.String("only", "", "run only the given stage of the recorded run")
+	flag.Parse()
+	if *from != "" && *only != "" {
+		fmt.Fprintln(os.Stderr, "--from and --only can't be used together")
+		return exitSetup
+	}
+	if err := teardownPolicy(*teardown).Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid --teardown:", err)
+		return exitSetup
+	}
+
+	// Interrupting the run cancels the running stage, so that cleanup is
+	// still done. Once it's interrupted, or teardown starts, the signals
+	// are no longer caught, so that a second interrupt kills the runner,
+	// e.g. while it's destroying the devenv.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
+	ctx, endTracing := startTracing(ctx)
+	defer func() { endTracing(code) }()
+
 	conf, err := box.EnsureBoxWithOptions(ctx)
 	if err != nil {
-		//nolint:gocritic // Why: We're OK with this.
-		log.Fatal().Err(err).Msg("Failed to load box config")
+		log.Error().Err(err).Msg("Failed to load box config")
+		return exitSetup
 	}
 
 	if conf.DeveloperEnvironmentConfig.VaultConfig.Enabled {
@@ -323,174 +197,170 @@ func main() { //nolint:funlen,gocyclo // Why: there are no reusable parts to ext
 		os.Setenv("VAULT_ADDR", vaultAddr)
 	}
 
-	// No or_e2e build tags were found.
-	runE2ETests, err := shouldRunE2ETests()
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to determine if e2e tests should be run")
-	}
-	if !runE2ETests {
-		log.Info().Msg("found no occurrences of or_e2e build tags, skipping e2e tests")
-		return
+	if err := validateConfig(); err != nil {
+		log.Error().Msgf("Invalid configuration:\n%s", err)
+		return exitSetup
 	}
 
-	// USE_DEVSPACE env var is used to onboard in cluster run of e2e tests using devspace
-	useDevspace := os.Getenv("USE_DEVSPACE") == "true" //nolint:goconst // Why: true == true
-	if useDevspace {
-		err := runE2ETestsUsingDevspace(ctx, conf)
-		if err != nil {
-			log.Fatal().Err(err).Msgf("Error in running e2e tests using devspace")
+	opts, err := deps.OptionsFromEnv()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to configure dependency resolution")
+		return exitSetup
+	}
+	opts.Refresh = opts.Refresh || *refresh
+	if *optionalDeps != "" {
+		opts.Optional = config.OptionalPolicy(*optionalDeps)
+		if err := opts.Optional.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid --optional-deps")
+			return exitSetup
 		}
-		return
+	}
+	if *maxDepth != 0 {
+		opts.MaxDepth = *maxDepth
+	}
+	opts.Profile = *profile
+	dg := &dependencyGraph{
+		conf:         conf,
+		opts:         opts,
+		ex:           tracedExecutor{ex: osExecutor{}},
+		waves:        *deployWaves,
+		readyTimeout: env.ReadyTimeout,
 	}
 
-	log.Info().Msg("Building dependency tree")
-
-	// Provision a devenv if it doesn't already exist. If it does exist,
-	// warn the user their test is no longer potentially reproducible.
-	// Allow skipping provision, this is generally only useful for the devenv
-	// which uses this framework -- but provisions itself.
-	if os.Getenv("SKIP_DEVENV_PROVISION") != "true" {
-		if !isDevenvProvisioned(ctx) {
-			var wg sync.WaitGroup
-			dockerBuilt := false
-			wg.Add(1)
-
-			// Build docker sooner and out of critical path to speed things up.
-			// Docker build in devenv apps deploy . will be superfast then.
-			go func(wg *sync.WaitGroup) {
-				defer wg.Done()
-				log.Info().Msg("Starting early docker build")
-				if err := exec.CommandContext(ctx, "make", "docker-build").Run(); err != nil {
-					log.Warn().Err(err).Msg("Error when running early docker build")
-				} else {
-					log.Info().Msg("Early docker build finished successfully")
-				}
-				dockerBuilt = true
-			}(&wg)
-
-			err := provisionDevenv(ctx, conf)
-			if err != nil {
-				//nolint:gocritic // Why: need to get exit code >0
-				log.Fatal().Err(err).Msg("Failed to provision devenv")
-				return
-			}
-
-			if !dockerBuilt {
-				log.Info().Msg("Waiting for docker build to finish")
-			}
-
-			wg.Wait() // To ensure that docker build is finished
-		} else {
-			log.Info().
-				//nolint:lll // Why: Message to user
-				Msg(devenvAlreadyExists)
+	if *depsGraph != "" {
+		if err := printDependencyGraph(ctx, dg, *depsGraph); err != nil {
+			log.Error().Err(err).Msg("Failed to print dependency graph")
+			return exitSetup
 		}
+		return exitOK
 	}
 
-	dc, err := config.FromFile("devenv.yaml")
+	// No or_e2e build tags were found.
+	runE2ETests, err := shouldRunE2ETests()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to parse devenv.yaml, cannot run e2e tests for this repo")
+		log.Error().Err(err).Msg("Failed to determine if e2e tests should be run")
+		return exitSetup
 	}
-
-	var wg sync.WaitGroup
-	requireDevconfigAfterDeploy := os.Getenv("REQUIRE_DEVCONFIG_AFTER_DEPLOY") == "true"
-
-	if !requireDevconfigAfterDeploy {
-		wg.Add(1)
-		go func(wg *sync.WaitGroup) {
-			defer wg.Done()
-			log.Info().Msg("Running devconfig in background")
-			if err := runDevconfig(ctx); err != nil {
-				// Call cancel to hopefully communicate a signal back to other currently running commands to stop
-				// doing what they're doing. If we just exit (implicitly via log.Fatal) they likely will continue
-				// running.
-				cancel()
-
-				log.Fatal().Err(err).Msg("failed to run devconfig")
-			}
-			log.Info().Msg("Running devconfig in background finished")
-		}(&wg)
+	if !runE2ETests {
+		log.Info().Msg("found no occurrences of or_e2e build tags, skipping e2e tests")
+		return exitOK
 	}
 
-	if dc.Service {
-		log.Info().Msg("Deploying current application into cluster")
-		if err := osStdInOutErr(exec.CommandContext(ctx, "devenv", "--skip-update", "apps", "deploy", "--with-deps", ".")).Run(); err != nil {
-			log.Fatal().Err(err).Msg("Failed to deploy current application into devenv")
-		}
-	} else {
-		// we want to build CLI application so that E2E tests can invoke it
-		log.Info().Msg("Building application")
-		if err := exec.CommandContext(ctx, "make", "build").Run(); err != nil {
-			log.Fatal().Err(err).Msg("Error building application")
-		} else {
-			log.Info().Msg("Build done")
-		}
+	if *plan {
+		// Only the plan is written, to stdout
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
 	}
 
-	if requireDevconfigAfterDeploy {
-		log.Info().Msg("Running devconfig")
-		if err := runDevconfig(ctx); err != nil {
-			log.Fatal().Err(err).Msg("failed to run devconfig")
-		}
-	} else {
-		wg.Wait() // Ensure that devconfig is done
+	r, err := newRunner(ctx, dg, env)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to set up the e2e run")
+		return exitSetup
 	}
+	r.teardownPolicy = teardownPolicy(*teardown)
 
-	// If the post-deploy script for e2e exists, run it.
-	if _, err := os.Stat("scripts/devenv/post-e2e-deploy.sh"); err == nil {
-		log.Info().Msg("Running scripts/devenv/post-e2e-deploy.sh")
-
-		if err := osStdInOutErr(exec.CommandContext(ctx, "scripts/devenv/post-e2e-deploy.sh")).Run(); err != nil {
-			log.Fatal().Err(err).Msg("Failed to run scripts/devenv/post-e2e-deploy.sh")
+	stages := r.stages()
+	if *from != "" || *only != "" {
+		if stages, err = r.resume(ctx, stages, *from+*only, *only != ""); err != nil {
+			log.Error().Err(err).Msg("Failed to resume the e2e run")
+			return exitSetup
 		}
 	}
 
-	// Allow users to opt out of running localizer
-	if os.Getenv("SKIP_LOCALIZER") != "true" {
-		closer, err := runLocalizer(ctx)
+	if *plan {
+		p, err := planRun(ctx, r, stages)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to run localizer")
+			log.Error().Err(err).Msg("Failed to plan the e2e run")
+			return exitSetup
 		}
-		defer closer()
+		if err := p.Write(os.Stdout, *planFormat); err != nil {
+			log.Error().Err(err).Msg("Failed to print the plan")
+			return exitSetup
+		}
+		return exitOK
 	}
 
-	log.Info().Msg("Running e2e tests")
-	os.Setenv("TEST_TAGS", "or_test,or_e2e")
-	if err := osStdInOutErr(exec.CommandContext(ctx, "./.bootstrap/shell/test.sh")).Run(); err != nil {
-		log.Fatal().Err(err).Msg("E2E tests failed, or failed to run")
+	// The devenv is torn down once cleanup, e.g. of the tunnel into it, is
+	// done, whether or not the run succeeded
+	success := false
+	defer func() {
+		stop()
+		r.teardown(success)
+	}()
+	defer r.cleanup()
+
+	r.track(ctx, stages)
+	if err := runStages(ctx, stages); err != nil {
+		var serr *stageError
+		if !errors.As(err, &serr) {
+			log.Error().Err(err).Msg("E2E run failed")
+			return 1
+		}
+		log.Error().Err(serr.err).Str("stage", serr.stage).Int("exit-code", serr.ExitCode()).Msg("E2E run failed")
+		return serr.ExitCode()
 	}
+	success = true
+	return exitOK
 }
 
 // provisionDevenv provisions devenv in correct target based on application dependencies
-func provisionDevenv(ctx context.Context, conf *box.Config) error {
-	deps, err := BuildDependenciesList(ctx, conf)
+func provisionDevenv(ctx context.Context, dg *dependencyGraph) error {
+	g, err := dg.Get(ctx)
 	if err != nil {
-		return errors.Wrap(err, "Failed to build dependency tree")
+		return err
 	}
+	services := g.Services()
 
-	// TODO(jaredallard): outreach specific code
-	target := "base"
-	if os.Getenv("PROVISION_TARGET") != "" {
-		target = os.Getenv("PROVISION_TARGET")
-	} else {
-		for _, d := range deps {
-			if d == "outreach" {
-				target = flagship
-				break
-			}
-		}
+	target, rule := provisionTarget(g)
+	if rule != nil {
+		log.Info().Str("dep", rule.Dependency).Str("target", rule.Target).Int("priority", rule.Priority).
+			Msg("Selected provision target by rule")
 	}
 
-	log.Info().Strs("deps", deps).Str("target", target).Msg("Provisioning devenv")
+	log.Info().Strs("deps", services).Str("target", target).Msg("Provisioning devenv")
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrProvisionTarget.String(target), tracing.AttrDepsCount.Int(len(services)))
+	if dg.waves {
+		log.Info().Interface("waves", g.Plan().Waves).Msg("Dependencies will be deployed in waves")
+	}
 
-	if err := provisionNew(ctx, target); err != nil {
+	if err := provisionNew(ctx, dg.ex, target); err != nil {
 		return errors.Wrap(err, "Failed to create cluster")
 	}
 	return nil
 }
 
-func isDevenvProvisioned(ctx context.Context) bool {
-	return exec.CommandContext(ctx, "devenv", "--skip-update", "status").Run() == nil
+// provisionTarget returns the provision target of the devenv: the
+// PROVISION_TARGET environment variable, or otherwise the target selected
+// by the provision target rules along with the rule that selected it
+func provisionTarget(g *deps.Graph) (string, *config.ProvisionTargetRule) {
+	if target := os.Getenv("PROVISION_TARGET"); target != "" {
+		return target, nil
+	}
+	return g.ProvisionTarget(provisionTargetConfigs()...)
+}
+
+// validateConfig validates the devenv.yaml and service.yaml of the
+// application, logging the warnings, e.g. keys of service.yaml added by
+// stencil modules that devbase doesn't know, and returning the errors
+func validateConfig() error {
+	err := config.ValidateFiles("devenv.yaml", "service.yaml")
+	var verrs config.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	warnings, severe := verrs.Split()
+	for _, w := range warnings {
+		log.Warn().Msg(w.Error())
+	}
+	if len(severe) == 0 {
+		return nil
+	}
+	return severe
+}
+
+// isDevenvProvisioned returns whether a devenv exists
+func isDevenvProvisioned(ctx context.Context, ex executor) bool {
+	return ex.Run(ctx, newCommand("devenv", "--skip-update", "status")) == nil
 }
 
 func runningInCi() bool {
@@ -6,7 +6,6 @@ package main
 
 import (
 	"context"
-	"os/exec"
 	"time"
 
 	"github.com/getoutreach/gobox/pkg/async"
@@ -20,7 +19,7 @@ import (
 
 // ensureRunningLocalizerWorks check if a localizer is already running, and if it is
 // ensure it's working properly (responding to pings). If it's not, remove the socket.
-func ensureRunningLocalizerWorks(ctx context.Context) error {
+func ensureRunningLocalizerWorks(ctx context.Context, ex executor) error {
 	log.Info().Msg("Ensuring existing localizer is actually running")
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
@@ -41,30 +40,44 @@ func ensureRunningLocalizerWorks(ctx context.Context) error {
 	}
 
 	// not responding to pings, or failed to connect, remove the socket
-	//nolint:gosec // Why: We're OK with this. It's a constant.
-	return osStdInOutErr(exec.Command("sudo", "rm", "-f", localizer.Socket)).Run()
+	// Not bound to the timeout of the ping, as sudo may prompt for a password
+	return ex.Run(context.Background(), stdInOutErr("sudo", "rm", "-f", localizer.Socket))
 }
 
-// runLocalizer runs localizer for devenv
-func runLocalizer(ctx context.Context) (cleanup func(), err error) {
+// startTunnel starts a devenv tunnel, which runs localizer, unless a
+// working localizer is already running. It returns whether it started one.
+func startTunnel(ctx context.Context, ex executor) (bool, error) {
 	if localizer.IsRunning() {
-		if err := ensureRunningLocalizerWorks(ctx); err != nil {
-			return nil, err
+		if err := ensureRunningLocalizerWorks(ctx, ex); err != nil {
+			return false, err
 		}
 	}
 
-	if !localizer.IsRunning() {
-		// Preemptively ask for sudo to prevent input mangling with o.LocalApps
-		log.Info().Msg("You may get a sudo prompt so localizer can create tunnels")
-		if err := osStdInOutErr(exec.CommandContext(ctx, "sudo", "true")).Run(); err != nil {
-			log.Fatal().Err(err).Msg("Failed to get root permissions")
-		}
+	if localizer.IsRunning() {
+		return false, nil
+	}
 
-		log.Info().Msg("Starting devenv tunnel")
-		if err := osStdInOutErr(exec.CommandContext(ctx, "devenv", "--skip-update", "tunnel")).Start(); err != nil {
-			log.Fatal().Err(err).Msg("Failed to start devenv tunnel")
-		}
+	// Preemptively ask for sudo to prevent input mangling with o.LocalApps
+	log.Info().Msg("You may get a sudo prompt so localizer can create tunnels")
+	if err := ex.Run(ctx, stdInOutErr("sudo", "true")); err != nil {
+		return false, errors.Wrap(err, "failed to get root permissions")
+	}
+
+	log.Info().Msg("Starting devenv tunnel")
+	if err := ex.Start(ctx, stdInOutErr("devenv", "--skip-update", "tunnel")); err != nil {
+		return false, errors.Wrap(err, "failed to start devenv tunnel")
+	}
+	return true, nil
+}
 
+// runLocalizer runs localizer for devenv
+func runLocalizer(ctx context.Context, ex executor) (cleanup func(), err error) {
+	started, err := startTunnel(ctx, ex)
+	if err != nil {
+		return nil, err
+	}
+
+	if started {
 		// Wait until localizer is running, max 1m
 		//nolint:govet // Why: done on purpose
 		ctx, cancel := context.WithDeadline(ctx, time.Now().Add(1*time.Minute))
@@ -82,7 +95,6 @@ func runLocalizer(ctx context.Context) (cleanup func(), err error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to connect to localizer")
 	}
-	defer closer()
 
 	log.Info().Msg("Waiting for devenv tunnel to be finished creating tunnels")
 	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(5*time.Minute))
@@ -91,6 +103,7 @@ func runLocalizer(ctx context.Context) (cleanup func(), err error) {
 	for ctx.Err() == nil {
 		resp, err := client.Stable(ctx, &localizerapi.Empty{})
 		if err != nil {
+			closer()
 			return nil, errors.Wrap(err, "failed to check if localizer is running")
 		}
 
@@ -101,7 +114,10 @@ func runLocalizer(ctx context.Context) (cleanup func(), err error) {
 		async.Sleep(ctx, time.Second*2)
 	}
 
+	// The connection is kept open until the tunnel is cleaned up, so that
+	// the localizer can be killed through it
 	return func() {
+		defer closer()
 		log.Info().Msg("Killing the spawned localizer process (spawned by devenv tunnel)")
 		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 		defer cancel()
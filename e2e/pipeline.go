@@ -0,0 +1,202 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the pipeline of stages the e2e runner is made of.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Contains the exit codes of the e2e runner that aren't specific to a stage
+const (
+	// exitOK is the exit code of a successful run
+	exitOK = 0
+
+	// exitSetup is the exit code when the runner failed before running any
+	// stage, e.g. because of invalid configuration
+	exitSetup = 2
+)
+
+// Contains the names of the stages of the e2e runner
+const (
+	stageResolve     = "resolve"
+	stageProvision   = "provision"
+	stageDockerBuild = "docker-build"
+	stageDevconfig   = "devconfig"
+	stageDeploy      = "deploy"
+	stagePostDeploy  = "post-deploy"
+	stageTunnel      = "tunnel"
+	stageTest        = "test"
+)
+
+// stageExitCodes are the exit codes of the e2e runner when a stage fails
+var stageExitCodes = map[string]int{
+	stageResolve:     10,
+	stageProvision:   11,
+	stageDockerBuild: 12,
+	stageDevconfig:   13,
+	stageDeploy:      14,
+	stagePostDeploy:  15,
+	stageTunnel:      16,
+	stageTest:        17,
+}
+
+// stage is a named step of the e2e runner
+type stage struct {
+	// name is the name of the stage, one of the stage* constants
+	name string
+
+	// run runs the stage
+	run func(ctx context.Context) error
+
+	// skip is why the stage is skipped, it's run when empty
+	skip string
+
+	// background runs the stage in the background, the next stage is
+	// started right away. When it fails, the stages still running are
+	// canceled and its error is returned.
+	background bool
+
+	// waitFor are the names of the background stages that need to have
+	// finished before this stage is run
+	waitFor []string
+}
+
+// stageError is the error of a failed stage
+type stageError struct {
+	// stage is the name of the stage that failed
+	stage string
+
+	// err is the error the stage failed with
+	err error
+}
+
+// Error implements error
+func (e *stageError) Error() string {
+	return fmt.Sprintf("stage %s failed: %s", e.stage, e.err)
+}
+
+// Unwrap returns the error the stage failed with
+func (e *stageError) Unwrap() error {
+	return e.err
+}
+
+// ExitCode returns the exit code of the e2e runner for the failed stage
+func (e *stageError) ExitCode() int {
+	if code, ok := stageExitCodes[e.stage]; ok {
+		return code
+	}
+	return 1
+}
+
+// runStages runs the given stages in order. Once a stage fails, in the
+// foreground or in the background, the stages still running are canceled
+// and waited for, and the error of the stage that failed first is
+// returned as a *stageError.
+func runStages(ctx context.Context, stages []*stage) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// pending are the results of the stages running in the background
+	pending := make(map[string]chan error)
+	var order []string
+
+	// first is the error of the stage that failed first, the stages
+	// failing after it are usually canceled by it
+	var mu sync.Mutex
+	var first error
+
+	// failed records the error of a failed stage and cancels the stages
+	// that are still running, returning the error of the stage that
+	// failed first
+	failed := func(err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if first == nil && err != nil {
+			first = err
+			cancel()
+		}
+		return first
+	}
+
+	// wait waits for a background stage to finish
+	wait := func(name string) error {
+		ch, ok := pending[name]
+		if !ok {
+			return nil
+		}
+		delete(pending, name)
+		if err := <-ch; err != nil {
+			return &stageError{stage: name, err: err}
+		}
+		log.Info().Str("stage", name).Msg("Background stage finished")
+		return nil
+	}
+
+	// fail cancels, and waits for, the background stages before returning
+	// the error of the stage that failed first
+	fail := func(err error) error {
+		failed(err)
+		for _, name := range order {
+			if ch, ok := pending[name]; ok {
+				<-ch
+			}
+		}
+		return failed(nil)
+	}
+
+	for _, s := range stages {
+		for _, name := range s.waitFor {
+			if err := wait(name); err != nil {
+				return fail(err)
+			}
+		}
+
+		// A background stage failed while the previous stage was running
+		if err := failed(nil); err != nil {
+			return fail(err)
+		}
+
+		if s.skip != "" {
+			log.Info().Str("stage", s.name).Str("reason", s.skip).Msg("Skipping stage")
+			//nolint:errcheck // Why: Skipped stages only record their span
+			runStage(ctx, s)
+			continue
+		}
+
+		if s.background {
+			log.Info().Str("stage", s.name).Msg("Starting stage in the background")
+			ch := make(chan error, 1)
+			pending[s.name] = ch
+			order = append(order, s.name)
+			go func(s *stage) {
+				err := runStage(ctx, s)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Error().Err(err).Str("stage", s.name).Msg("Background stage failed, canceling the run")
+					}
+					failed(&stageError{stage: s.name, err: err})
+				}
+				ch <- err
+			}(s)
+			continue
+		}
+
+		log.Info().Str("stage", s.name).Msg("Running stage")
+		if err := runStage(ctx, s); err != nil {
+			return fail(&stageError{stage: s.name, err: err})
+		}
+	}
+
+	for _, name := range order {
+		if err := wait(name); err != nil {
+			return fail(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder records the stages that ran, in order
+type recorder struct {
+	mu  sync.Mutex
+	ran []string
+}
+
+// stage returns a stage that records it ran and returns err
+func (r *recorder) stage(name string, err error) *stage {
+	return &stage{name: name, run: func(ctx context.Context) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.ran = append(r.ran, name)
+		return err
+	}}
+}
+
+func TestRunStages(t *testing.T) {
+	var r recorder
+	skipped := r.stage(stagePostDeploy, nil)
+	skipped.skip = "no script"
+
+	assert.NoError(t, runStages(context.Background(), []*stage{
+		r.stage(stageResolve, nil),
+		r.stage(stageDeploy, nil),
+		skipped,
+		r.stage(stageTest, nil),
+	}))
+	assert.Equal(t, []string{stageResolve, stageDeploy, stageTest}, r.ran)
+}
+
+func TestRunStagesFailure(t *testing.T) {
+	var r recorder
+	err := runStages(context.Background(), []*stage{
+		r.stage(stageResolve, nil),
+		r.stage(stageDeploy, errors.New("boom")),
+		r.stage(stageTest, nil),
+	})
+
+	var serr *stageError
+	assert.ErrorAs(t, err, &serr)
+	assert.Equal(t, stageDeploy, serr.stage)
+	assert.Equal(t, 14, serr.ExitCode())
+	assert.EqualError(t, err, "stage deploy failed: boom")
+	assert.Equal(t, []string{stageResolve, stageDeploy}, r.ran)
+}
+
+func TestRunStagesBackground(t *testing.T) {
+	// A failed background stage fails the stage waiting for it
+	started := make(chan struct{})
+	provision := &stage{name: stageProvision, background: true, run: func(ctx context.Context) error {
+		<-started
+		return errors.New("no cluster")
+	}}
+
+	var r recorder
+	build := r.stage(stageDockerBuild, nil)
+	build.run = func(ctx context.Context) error {
+		close(started)
+		return nil
+	}
+	deploy := r.stage(stageDeploy, nil)
+	deploy.waitFor = []string{stageProvision}
+
+	err := runStages(context.Background(), []*stage{provision, build, deploy})
+	var serr *stageError
+	assert.ErrorAs(t, err, &serr)
+	assert.Equal(t, stageProvision, serr.stage)
+	assert.Empty(t, r.ran)
+
+	// A failed stage cancels the stages running in the background
+	canceled := &stage{name: stageDevconfig, background: true, run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	err = runStages(context.Background(), []*stage{canceled, r.stage(stageDeploy, errors.New("boom"))})
+	assert.ErrorAs(t, err, &serr)
+	assert.Equal(t, stageDeploy, serr.stage)
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the plan mode of the e2e runner, which shows what a run would do.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/pkg/errors"
+)
+
+// planExecutor is the executor of a plan, it records the commands instead
+// of running them. Every command succeeds without output.
+type planExecutor struct {
+	mu   sync.Mutex
+	cmds []*command
+}
+
+// record records a command
+func (e *planExecutor) record(c *command) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cmds = append(e.cmds, c)
+}
+
+// take returns the commands recorded since it was last called
+func (e *planExecutor) take() []*command {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cmds := e.cmds
+	e.cmds = nil
+	return cmds
+}
+
+// Run implements executor
+func (e *planExecutor) Run(_ context.Context, c *command) error {
+	e.record(c)
+	return nil
+}
+
+// Output implements executor
+func (e *planExecutor) Output(_ context.Context, c *command) ([]byte, error) {
+	e.record(c)
+	return nil, nil
+}
+
+// Start implements executor
+func (e *planExecutor) Start(_ context.Context, c *command) error {
+	e.record(c)
+	return nil
+}
+
+// runPlan is what an e2e run would do
+type runPlan struct {
+	// Dependencies are the resolved dependencies of the application, nil
+	// when the run doesn't resolve them, see runner.needsGraph
+	Dependencies []string `json:"dependencies"`
+
+	// Waves are the waves the dependencies are deployed in, when deploying
+	// in waves
+	Waves [][]string `json:"waves,omitempty"`
+
+	// Profile is the devenv.yaml profile whose dependencies are deployed,
+	// if any
+	Profile string `json:"profile,omitempty"`
+
+	// ProvisionTarget is the provision target of the devenv
+	ProvisionTarget string `json:"provisionTarget"`
+
+	// ProvisionRule is the provision target rule that selected the
+	// provision target, if any
+	ProvisionRule *config.ProvisionTargetRule `json:"provisionRule,omitempty"`
+
+	// ClusterExists denotes if a devenv already exists
+	ClusterExists bool `json:"clusterExists"`
+
+	// DestroyCluster denotes if an existing devenv is destroyed before
+	// provisioning a new one
+	DestroyCluster bool `json:"destroyCluster"`
+
+	// Teardown is when the devenv is destroyed once the run is done
+	Teardown teardownPolicy `json:"teardown"`
+
+	// Stages are the stages of the run, in order
+	Stages []plannedStage `json:"stages"`
+}
+
+// plannedStage is a stage of a runPlan
+type plannedStage struct {
+	// Name is the name of the stage
+	Name string `json:"name"`
+
+	// Skip is why the stage is skipped, empty when it's run
+	Skip string `json:"skip,omitempty"`
+
+	// Background denotes if the stage runs in the background
+	Background bool `json:"background,omitempty"`
+
+	// Commands are the commands the stage runs, in order. Commands of
+	// concurrent work within a stage may be run in a different order.
+	Commands []*command `json:"commands"`
+}
+
+// planRun returns what the run of the stages would do. Dependencies are
+// resolved, when the run would, and the devenv is inspected, but no
+// command of a stage is executed.
+func planRun(ctx context.Context, r *runner, stages []*stage) (*runPlan, error) {
+	ex := &planExecutor{}
+	r.ex, r.dg.ex, r.dryRun = ex, ex, true
+
+	p := &runPlan{Profile: r.dg.opts.Profile, ClusterExists: r.provisioned}
+	if r.needsGraph() {
+		g, err := r.dg.Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Dependencies = g.Services()
+		p.ProvisionTarget, p.ProvisionRule = provisionTarget(g)
+		if r.dg.waves {
+			p.Waves = g.Plan().Waves
+		}
+	}
+
+	for _, s := range stages {
+		ps := plannedStage{Name: s.name, Skip: s.skip, Background: s.background, Commands: []*command{}}
+		if s.skip == "" {
+			if err := s.run(ctx); err != nil {
+				return nil, errors.Wrapf(err, "failed to plan stage %s", s.name)
+			}
+			ps.Commands = append(ps.Commands, ex.take()...)
+		}
+		if s.name == stageProvision && s.skip == "" {
+			p.DestroyCluster = true
+		}
+		p.Stages = append(p.Stages, ps)
+	}
+	// Planning the provision stage denotes the devenv is created by the run
+	p.Teardown = r.effectiveTeardown()
+
+	return p, nil
+}
+
+// Write writes the plan to w in the given format, text or json
+func (p *runPlan) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	case "text", "":
+		return p.writeText(w)
+	default:
+		return fmt.Errorf("unknown plan format %q, expected one of [text json]", format)
+	}
+}
+
+// writeText writes the plan as human readable text
+func (p *runPlan) writeText(w io.Writer) error {
+	var b strings.Builder
+	if p.Dependencies == nil {
+		b.WriteString("Dependencies: not resolved, nothing in the run needs them\n")
+	} else {
+		fmt.Fprintf(&b, "Dependencies: %s\n", orNone(strings.Join(p.Dependencies, ", ")))
+	}
+	for i, wave := range p.Waves {
+		fmt.Fprintf(&b, "  Wave %d: %s\n", i+1, strings.Join(wave, ", "))
+	}
+	switch {
+	case p.Profile != "" && len(p.Waves) > 0:
+		fmt.Fprintf(&b, "Profile: %s, its dependencies are deployed in waves\n", p.Profile)
+	case p.Profile != "":
+		fmt.Fprintf(&b, "Profile: %s, its top-level dependencies are deployed with theirs\n", p.Profile)
+	}
+
+	fmt.Fprintf(&b, "Provision target: %s", orNone(p.ProvisionTarget))
+	if p.ProvisionRule != nil {
+		fmt.Fprintf(&b, " (rule: dependency %s, priority %d)", p.ProvisionRule.Dependency, p.ProvisionRule.Priority)
+	}
+	b.WriteString("\n")
+
+	switch {
+	case p.DestroyCluster && p.ClusterExists:
+		b.WriteString("Cluster: the existing devenv is destroyed, and a new one provisioned\n")
+	case p.DestroyCluster:
+		b.WriteString("Cluster: a new devenv is provisioned\n")
+	case p.ClusterExists:
+		b.WriteString("Cluster: the existing devenv is reused\n")
+	default:
+		b.WriteString("Cluster: none is provisioned\n")
+	}
+
+	switch p.Teardown {
+	case teardownAlways:
+		b.WriteString("Teardown: always, the devenv is destroyed once the run is done\n")
+	case teardownOnSuccess:
+		b.WriteString("Teardown: on-success, the devenv is destroyed when the run succeeds\n")
+	default:
+		b.WriteString("Teardown: never, the devenv is left\n")
+	}
+
+	b.WriteString("Stages:\n")
+	for _, s := range p.Stages {
+		switch {
+		case s.Skip != "":
+			fmt.Fprintf(&b, "  %s: skipped, %s\n", s.Name, s.Skip)
+		case s.Background:
+			fmt.Fprintf(&b, "  %s (background)\n", s.Name)
+		default:
+			fmt.Fprintf(&b, "  %s\n", s.Name)
+		}
+		for _, c := range s.Commands {
+			fmt.Fprintf(&b, "    $ %s\n", c)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// orNone returns s, or "none" when it's empty
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
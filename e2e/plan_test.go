@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandString(t *testing.T) {
+	c := newCommand("git", "commit", "-m", "it's done", "").withEnv("A=b")
+	assert.Equal(t, `A=b git commit -m 'it'\''s done' ''`, c.String())
+}
+
+func TestRunPlanWrite(t *testing.T) {
+	p := &runPlan{
+		Dependencies:    []string{"flagship", "mint"},
+		Waves:           [][]string{{"mint"}, {"flagship"}},
+		Profile:         "minimal",
+		ProvisionTarget: "flagship",
+		ProvisionRule:   &config.ProvisionTargetRule{Dependency: "flagship", Target: "flagship", Priority: 1},
+		ClusterExists:   true,
+		DestroyCluster:  true,
+		Teardown:        teardownAlways,
+		Stages: []plannedStage{
+			{Name: stageProvision, Background: true, Commands: []*command{
+				newCommand("devenv", "--skip-update", "destroy"),
+			}},
+			{Name: stagePostDeploy, Skip: "it doesn't exist", Commands: []*command{}},
+			{Name: stageTest, Commands: []*command{
+				stdInOutErr("./.bootstrap/shell/test.sh").withEnv("TEST_TAGS=or_test,or_e2e"),
+			}},
+		},
+	}
+
+	var b bytes.Buffer
+	assert.NoError(t, p.Write(&b, "text"))
+	assert.Equal(t, strings.Join([]string{
+		"Dependencies: flagship, mint",
+		"  Wave 1: mint",
+		"  Wave 2: flagship",
+		"Profile: minimal, its dependencies are deployed in waves",
+		"Provision target: flagship (rule: dependency flagship, priority 1)",
+		"Cluster: the existing devenv is destroyed, and a new one provisioned",
+		"Teardown: always, the devenv is destroyed once the run is done",
+		"Stages:",
+		"  provision (background)",
+		"    $ devenv --skip-update destroy",
+		"  post-deploy: skipped, it doesn't exist",
+		"  test",
+		"    $ TEST_TAGS=or_test,or_e2e ./.bootstrap/shell/test.sh",
+		"",
+	}, "\n"), b.String())
+
+	b.Reset()
+	assert.NoError(t, p.Write(&b, "json"))
+	assert.Contains(t, b.String(), `"provisionRule": {
+    "dependency": "flagship",
+    "target": "flagship",
+    "priority": 1
+  }`)
+	assert.Contains(t, b.String(), `"env": [
+            "TEST_TAGS=or_test,or_e2e"
+          ]`)
+
+	assert.Error(t, p.Write(&b, "yaml"))
+
+	b.Reset()
+	p.Dependencies, p.Waves = nil, nil
+	assert.NoError(t, p.Write(&b, "text"))
+	assert.Contains(t, b.String(), "Dependencies: not resolved, nothing in the run needs them\n")
+	assert.Contains(t, b.String(), "Profile: minimal, its top-level dependencies are deployed with theirs\n")
+}
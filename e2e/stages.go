@@ -0,0 +1,362 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the stages of an e2e run.
+
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/pkg/manifest"
+	"github.com/getoutreach/devbase/v2/pkg/tracing"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+// postDeployScript is run, when it exists, after the application has been
+// deployed
+const postDeployScript = "scripts/devenv/post-e2e-deploy.sh"
+
+// fileStater abstracts os.Stat
+type fileStater = func(name string) (os.FileInfo, error)
+
+// fileReader abstracts os.ReadFile
+type fileReader = func(name string) ([]byte, error)
+
+// manifestLoader abstracts manifest.Load
+type manifestLoader = func(dir string) (*manifest.Service, error)
+
+// tunnelOpener abstracts runLocalizer
+type tunnelOpener = func(ctx context.Context, ex executor) (func(), error)
+
+// runner is a single e2e run, made of stages
+type runner struct {
+	// dg is the dependency graph of the application
+	dg *dependencyGraph
+
+	// dc is the devenv.yaml of the application
+	dc *config.Devenv
+
+	// ex executes the commands of the stages
+	ex executor
+
+	// stat, readFile, loadManifest and openTunnel are the side effects of
+	// the stages other than commands
+	stat         fileStater
+	readFile     fileReader
+	loadManifest manifestLoader
+	openTunnel   tunnelOpener
+
+	// dryRun denotes that the commands are only planned, not executed, see
+	// planRun. Stages skip what can't be done without executing commands.
+	dryRun bool
+
+	// provisioned denotes if a devenv already existed before the run
+	provisioned bool
+
+	// created denotes if the devenv was provisioned by the run, or by the
+	// recorded run it resumed
+	created bool
+
+	// teardownPolicy is when the devenv is destroyed once the run is
+	// done, when empty the default of effectiveTeardown is used
+	teardownPolicy teardownPolicy
+
+	// skipProvision skips provisioning a devenv, SKIP_DEVENV_PROVISION
+	skipProvision bool
+
+	// skipLocalizer skips creating a tunnel, SKIP_LOCALIZER
+	skipLocalizer bool
+
+	// devconfigAfterDeploy runs devconfig after, instead of while,
+	// deploying, REQUIRE_DEVCONFIG_AFTER_DEPLOY
+	devconfigAfterDeploy bool
+
+	// useDevspace runs the tests in a devspace pod, USE_DEVSPACE
+	useDevspace bool
+
+	// statePath is the file the state of the run is recorded in, see track
+	statePath string
+
+	// state is the recorded state of the run, set when it's resumed or
+	// tracked
+	state *runState
+
+	// cleanups are run, in reverse order, once the run is done
+	cleanups []func()
+}
+
+// newRunner creates the runner of an e2e run configured by the
+// environment, see config.RunnerFromEnv
+func newRunner(ctx context.Context, dg *dependencyGraph, env *config.Runner) (*runner, error) {
+	r := &runner{
+		dg:                   dg,
+		ex:                   dg.ex,
+		stat:                 os.Stat,
+		readFile:             os.ReadFile,
+		loadManifest:         manifest.Load,
+		openTunnel:           runLocalizer,
+		statePath:            stateFile,
+		provisioned:          isDevenvProvisioned(ctx, dg.ex),
+		skipProvision:        env.SkipProvision,
+		skipLocalizer:        env.SkipLocalizer,
+		devconfigAfterDeploy: env.DevconfigAfterDeploy,
+		// USE_DEVSPACE env var is used to onboard in cluster run of e2e tests using devspace
+		useDevspace: env.UseDevspace,
+	}
+
+	// The devspace run doesn't need devenv.yaml itself
+	dc, err := config.FromFile("devenv.yaml")
+	if err != nil && !r.useDevspace {
+		return nil, errors.Wrap(err, "Failed to parse devenv.yaml, cannot run e2e tests for this repo")
+	}
+	r.dc = dc
+	dg.pinned = dc != nil && dc.Dependencies.Pinned()
+	return r, nil
+}
+
+// needsGraph returns whether the run needs the dependency graph, which
+// is only resolved then: to provision a devenv, or to deploy the
+// dependencies, see dependencyGraph.Needed. Other runs don't need access
+// to the forge.
+func (r *runner) needsGraph() bool {
+	provision := !r.provisioned && (r.useDevspace || !r.skipProvision)
+	return provision || r.dg.Needed()
+}
+
+// resolveStage returns the stage that resolves the dependency graph
+func (r *runner) resolveStage() *stage {
+	s := &stage{name: stageResolve, run: r.resolve}
+	if !r.needsGraph() {
+		s.skip = "nothing in the run needs the dependency tree"
+	}
+	return s
+}
+
+// cleanup runs the cleanups of the run, in reverse order
+func (r *runner) cleanup() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+	r.cleanups = nil
+}
+
+// stages returns the stages of the run, in the order they're run
+func (r *runner) stages() []*stage {
+	if r.useDevspace {
+		return r.devspaceStages()
+	}
+
+	provision := &stage{name: stageProvision, run: r.provision}
+	// Build docker sooner and out of critical path to speed things up.
+	// Docker build in devenv apps deploy . will be superfast then.
+	dockerBuild := &stage{name: stageDockerBuild, run: r.dockerBuild}
+	switch {
+	case r.skipProvision:
+		provision.skip = "SKIP_DEVENV_PROVISION is set"
+		dockerBuild.skip = "no devenv is provisioned"
+	case r.provisioned:
+		log.Info().Msg(devenvAlreadyExists)
+		provision.skip = "the devenv already exists"
+		dockerBuild.skip = "the devenv already exists, the image is built when deploying"
+	default:
+		provision.background = true
+	}
+
+	devconfig := &stage{name: stageDevconfig, run: r.devconfig, waitFor: []string{stageProvision}}
+	deploy := &stage{name: stageDeploy, run: r.deploy, waitFor: []string{stageProvision}}
+	postDeploy := &stage{name: stagePostDeploy, run: r.postDeploy, waitFor: []string{stageDevconfig}}
+	tunnel := &stage{name: stageTunnel, run: r.tunnel}
+	if r.skipLocalizer {
+		tunnel.skip = "SKIP_LOCALIZER is set"
+	}
+	test := &stage{name: stageTest, run: r.test}
+
+	if r.devconfigAfterDeploy {
+		return []*stage{r.resolveStage(), provision, dockerBuild, deploy, devconfig, postDeploy, tunnel, test}
+	}
+	devconfig.background = true
+	return []*stage{r.resolveStage(), provision, dockerBuild, devconfig, deploy, postDeploy, tunnel, test}
+}
+
+// resolve resolves the dependency graph of the application
+func (r *runner) resolve(ctx context.Context) error {
+	log.Info().Msg("Building dependency tree")
+	g, err := r.dg.Get(ctx)
+	if err != nil {
+		return err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrDepsCount.Int(len(g.Services())))
+	return nil
+}
+
+// provision provisions a new devenv
+func (r *runner) provision(ctx context.Context) error {
+	r.created = true
+	return provisionDevenv(ctx, r.dg)
+}
+
+// dockerBuild builds the docker image of the application early, while
+// the devenv is being provisioned. It's only an optimization, failures
+// are left to deploying the application.
+func (r *runner) dockerBuild(ctx context.Context) error {
+	log.Info().Msg("Starting early docker build")
+	if err := r.ex.Run(ctx, newCommand("make", "docker-build")); err != nil {
+		log.Warn().Err(err).Msg("Error when running early docker build")
+		return nil
+	}
+	log.Info().Msg("Early docker build finished successfully")
+	return nil
+}
+
+// devconfig runs devconfig
+func (r *runner) devconfig(ctx context.Context) error {
+	log.Info().Msg("Running devconfig")
+	if err := runDevconfig(ctx, r.ex); err != nil {
+		return errors.Wrap(err, "failed to run devconfig")
+	}
+	return nil
+}
+
+// deploy deploys the application with its dependencies into the devenv,
+// or for a library builds it and deploys its pinned dependencies
+func (r *runner) deploy(ctx context.Context) error {
+	if r.dc.Service {
+		log.Info().Msg("Deploying current application into cluster")
+		if err := deployWithDependencies(ctx, r.dg, "."); err != nil {
+			return errors.Wrap(err, "Failed to deploy current application into devenv")
+		}
+		return nil
+	}
+
+	// we want to build CLI application so that E2E tests can invoke it
+	log.Info().Msg("Building application")
+	if err := r.ex.Run(ctx, newCommand("make", "build")); err != nil {
+		return errors.Wrap(err, "Error building application")
+	}
+	log.Info().Msg("Build done")
+
+	if err := deployPinnedDependencies(ctx, r.dg); err != nil {
+		return errors.Wrap(err, "Failed to deploy pinned dependencies into devenv")
+	}
+	return nil
+}
+
+// postDeploy runs the post-deploy script of the application, when it
+// exists. It's looked for only now, as deploying or devconfig may create
+// it.
+func (r *runner) postDeploy(ctx context.Context) error {
+	if _, err := r.stat(postDeployScript); err != nil {
+		log.Info().Msgf("Not running %s, it doesn't exist", postDeployScript)
+		return nil
+	}
+
+	log.Info().Msgf("Running %s", postDeployScript)
+	if err := r.ex.Run(ctx, stdInOutErr(postDeployScript)); err != nil {
+		return errors.Wrapf(err, "Failed to run %s", postDeployScript)
+	}
+	return nil
+}
+
+// tunnel creates a localizer tunnel into the devenv, which is closed
+// when the run is cleaned up
+func (r *runner) tunnel(ctx context.Context) error {
+	if r.dryRun {
+		// Without a tunnel there's nothing to wait for
+		_, err := startTunnel(ctx, r.ex)
+		return err
+	}
+
+	closer, err := r.openTunnel(ctx, r.ex)
+	if err != nil {
+		return errors.Wrap(err, "Failed to run localizer")
+	}
+	r.cleanups = append(r.cleanups, closer)
+	return nil
+}
+
+// test runs the e2e tests
+func (r *runner) test(ctx context.Context) error {
+	log.Info().Msg("Running e2e tests")
+	if err := r.ex.Run(ctx, stdInOutErr("./.bootstrap/shell/test.sh").withEnv("TEST_TAGS=or_test,or_e2e")); err != nil {
+		return errors.Wrap(err, "E2E tests failed, or failed to run")
+	}
+	return nil
+}
+
+// devspaceStages returns the stages of a run that uses devspace and
+// binary sync to deploy the application. There's no devconfig and docker
+// build.
+func (r *runner) devspaceStages() []*stage {
+	provision := &stage{name: stageProvision, run: r.provision}
+	if r.provisioned {
+		log.Info().Msg(devenvAlreadyExists)
+		provision.skip = "the devenv already exists"
+	}
+
+	return []*stage{
+		r.resolveStage(),
+		provision,
+		{name: stageDeploy, run: r.deployDevspace},
+		{name: stageTest, run: r.testDevspace},
+	}
+}
+
+// deployDevspace deploys the latest stable version of the application,
+// and its dependencies, while building the binaries that are synced into
+// the devspace pod
+func (r *runner) deployDevspace(ctx context.Context) error {
+	svc, err := r.loadManifest(".")
+	if err != nil {
+		return err
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		log.Info().Msg("Building binaries for devspace pod")
+		if err := r.ex.Run(ctx, stdInOutErr("make", "devspace")); err != nil {
+			return errors.Wrap(err, "Error when building for devspace")
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		log.Info().Msgf("Deploying latest stable version of %s application into cluster together with dependencies", svc.Name)
+		if err := deployWithDependencies(ctx, r.dg, svc.Name); err != nil {
+			return errors.Wrapf(err, "Failed to deploy %s into devenv", svc.Name)
+		}
+		return nil
+	})
+	return eg.Wait()
+}
+
+// testDevspace starts the devspace pod, which runs the e2e tests, and
+// checks their results
+func (r *runner) testDevspace(ctx context.Context) error {
+	log.Info().Msg("Starting devspace pod and running e2e tests")
+	if err := r.ex.Run(ctx, stdInOutErr("devenv", "--skip-update", "apps", "e2e", "--sync-binaries", ".")); err != nil {
+		return errors.Wrap(err, "Failed to run e2e tests in devspace pod")
+	}
+	if runningInCi() {
+		// Copy junit report to place where CircleCi expects it
+		if err := r.ex.Run(ctx, stdInOutErr("cp", junitTestResultPath, "/tmp/test-results/")); err != nil {
+			return errors.Wrap(err, "Unable to copy tests results to CircleCI artifact path")
+		}
+	}
+	if r.dryRun {
+		// There are no results without running the tests
+		return nil
+	}
+	testsSuccess, err := parseResultFromJunitReport(r.readFile)
+	if err != nil {
+		return err
+	}
+	if !testsSuccess {
+		return errors.New("E2E Tests failed")
+	}
+	log.Info().Msg("E2E Tests succeeded.")
+	return nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/e2e/deps"
+	"github.com/getoutreach/devbase/v2/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExecutor records the commands it's given, returning the scripted
+// error and output of a command, by its String
+type fakeExecutor struct {
+	mu   sync.Mutex
+	cmds []string
+
+	errs    map[string]error
+	outputs map[string][]byte
+
+	// blocking are the commands that run until they're canceled
+	blocking map[string]bool
+}
+
+// record records a command, returning its scripted error
+func (e *fakeExecutor) record(c *command) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cmds = append(e.cmds, c.String())
+	return e.errs[c.String()]
+}
+
+// Run implements executor
+func (e *fakeExecutor) Run(ctx context.Context, c *command) error {
+	if err := e.record(c); err != nil || !e.blocking[c.String()] {
+		return err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// Output implements executor
+func (e *fakeExecutor) Output(_ context.Context, c *command) ([]byte, error) {
+	err := e.record(c)
+	return e.outputs[c.String()], err
+}
+
+// Start implements executor
+func (e *fakeExecutor) Start(_ context.Context, c *command) error {
+	return e.record(c)
+}
+
+// assertBefore asserts that every command of before was run before every
+// command of after
+func (e *fakeExecutor) assertBefore(t *testing.T, before, after string) {
+	t.Helper()
+	bi, ai := -1, -1
+	for i, c := range e.cmds {
+		if c == before {
+			bi = i
+		}
+		if c == after && ai == -1 {
+			ai = i
+		}
+	}
+	assert.True(t, bi != -1 && ai != -1 && bi < ai, "expected %q to run before %q in %q", before, after, e.cmds)
+}
+
+// Contains commands run by the stages
+const (
+	cmdDestroy   = "devenv --skip-update destroy"
+	cmdProvision = "devenv --skip-update provision --snapshot-target base"
+	cmdDocker    = "make docker-build"
+	cmdDevconfig = "./scripts/shell-wrapper.sh devconfig.sh"
+	cmdDeploy    = "devenv --skip-update apps deploy --with-deps ."
+	cmdBuild     = "make build"
+	cmdTest      = "TEST_TAGS=or_test,or_e2e ./.bootstrap/shell/test.sh"
+	cmdTunnel    = "tunnel"
+)
+
+// newTestRunner returns a runner of an application with a single
+// dependency, whose side effects are faked: there's no post-deploy
+// script and the tunnel is recorded as cmdTunnel
+func newTestRunner(t *testing.T, dc *config.Devenv) (*runner, *fakeExecutor, *bool) {
+	t.Setenv("PROVISION_TARGET", "base")
+	t.Setenv("CI", "false")
+
+	g := deps.NewGraph("app", "devenv.yaml")
+	g.AddNode("mint", "devenv.yaml")
+	g.AddEdge("app", "mint", true)
+
+	ex := &fakeExecutor{}
+	closed := false
+	r := &runner{
+		dg: &dependencyGraph{g: g, opts: &deps.Options{}, ex: ex, readyTimeout: config.DefaultReadyTimeout},
+		dc: dc,
+		ex: ex,
+		stat: func(name string) (os.FileInfo, error) {
+			return nil, os.ErrNotExist
+		},
+		readFile: func(name string) ([]byte, error) {
+			return nil, os.ErrNotExist
+		},
+		loadManifest: func(dir string) (*manifest.Service, error) {
+			return &manifest.Service{Name: "app"}, nil
+		},
+		openTunnel: func(ctx context.Context, ex executor) (func(), error) {
+			return func() { closed = true }, ex.Run(ctx, newCommand(cmdTunnel))
+		},
+	}
+	return r, ex, &closed
+}
+
+func TestRunnerService(t *testing.T) {
+	r, ex, closed := newTestRunner(t, &config.Devenv{Service: true})
+	assert.NoError(t, runStages(context.Background(), r.stages()))
+	r.cleanup()
+
+	assert.ElementsMatch(t, []string{
+		cmdDestroy, cmdProvision, cmdDocker, cmdDevconfig, cmdDeploy, cmdTunnel, cmdTest,
+	}, ex.cmds)
+	ex.assertBefore(t, cmdDestroy, cmdProvision)
+	ex.assertBefore(t, cmdProvision, cmdDevconfig)
+	ex.assertBefore(t, cmdProvision, cmdDeploy)
+	ex.assertBefore(t, cmdDeploy, cmdTunnel)
+	ex.assertBefore(t, cmdDevconfig, cmdTunnel)
+	ex.assertBefore(t, cmdTunnel, cmdTest)
+	assert.True(t, *closed, "tunnel should be closed on cleanup")
+}
+
+func TestRunnerServiceDevconfigAfterDeploy(t *testing.T) {
+	r, ex, _ := newTestRunner(t, &config.Devenv{Service: true})
+	r.provisioned = true
+	r.devconfigAfterDeploy = true
+	r.stat = func(name string) (os.FileInfo, error) { return nil, nil }
+	assert.NoError(t, runStages(context.Background(), r.stages()))
+
+	assert.Equal(t, []string{cmdDeploy, cmdDevconfig, postDeployScript, cmdTunnel, cmdTest}, ex.cmds)
+}
+
+func TestRunnerLibrary(t *testing.T) {
+	r, ex, _ := newTestRunner(t, &config.Devenv{})
+	r.provisioned = true
+	r.skipLocalizer = true
+	assert.NoError(t, runStages(context.Background(), r.stages()))
+
+	assert.ElementsMatch(t, []string{cmdDevconfig, cmdBuild, cmdTest}, ex.cmds)
+	ex.assertBefore(t, cmdBuild, cmdTest)
+	ex.assertBefore(t, cmdDevconfig, cmdTest)
+}
+
+func TestRunnerReusedDevenvDoesNotResolve(t *testing.T) {
+	r, ex, _ := newTestRunner(t, &config.Devenv{Service: true})
+	r.provisioned = true
+	r.devconfigAfterDeploy = true
+	// Resolving the graph would need box and the forge
+	r.dg.g = nil
+	stages := r.stages()
+	assert.NoError(t, runStages(context.Background(), stages))
+
+	assert.NotEmpty(t, stages[0].skip, "the resolve stage should be skipped")
+	assert.Nil(t, r.dg.g, "the dependency graph shouldn't be resolved")
+	assert.Equal(t, []string{cmdDeploy, cmdDevconfig, cmdTunnel, cmdTest}, ex.cmds)
+}
+
+func TestRunnerPostDeployScriptCreatedByDevconfig(t *testing.T) {
+	r, ex, _ := newTestRunner(t, &config.Devenv{Service: true})
+	r.provisioned = true
+	r.devconfigAfterDeploy = true
+	r.stat = func(name string) (os.FileInfo, error) {
+		if slices.Contains(ex.cmds, cmdDevconfig) {
+			return nil, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	assert.NoError(t, runStages(context.Background(), r.stages()))
+
+	assert.Equal(t, []string{cmdDeploy, cmdDevconfig, postDeployScript, cmdTunnel, cmdTest}, ex.cmds)
+}
+
+func TestRunnerProfile(t *testing.T) {
+	r, ex, _ := newTestRunner(t, &config.Devenv{Service: true})
+	r.provisioned = true
+	r.skipLocalizer = true
+	r.devconfigAfterDeploy = true
+	r.dg.opts.Profile = "minimal"
+	assert.NoError(t, runStages(context.Background(), r.stages()))
+
+	// Without --deploy-waves the dependencies aren't deployed in waves
+	assert.Equal(t, []string{
+		"devenv --skip-update apps deploy --with-deps mint",
+		"devenv --skip-update apps deploy .",
+		cmdDevconfig,
+		cmdTest,
+	}, ex.cmds)
+}
+
+func TestRunnerSkipProvision(t *testing.T) {
+	r, ex, _ := newTestRunner(t, &config.Devenv{Service: true})
+	r.skipProvision = true
+	r.devconfigAfterDeploy = true
+	assert.NoError(t, runStages(context.Background(), r.stages()))
+
+	assert.Equal(t, []string{cmdDeploy, cmdDevconfig, cmdTunnel, cmdTest}, ex.cmds)
+}
+
+func TestRunnerDevspace(t *testing.T) {
+	r, ex, _ := newTestRunner(t, nil)
+	t.Setenv("CI", "true")
+	r.useDevspace = true
+	r.readFile = func(name string) ([]byte, error) {
+		assert.Equal(t, junitTestResultPath, name)
+		return []byte(`<testsuites failures="0"></testsuites>`), nil
+	}
+	assert.NoError(t, runStages(context.Background(), r.stages()))
+
+	e2e := "devenv --skip-update apps e2e --sync-binaries ."
+	cp := "cp " + junitTestResultPath + " /tmp/test-results/"
+	deploy := "devenv --skip-update apps deploy --with-deps app"
+	assert.ElementsMatch(t, []string{cmdDestroy, cmdProvision, "make devspace", deploy, e2e, cp}, ex.cmds)
+	ex.assertBefore(t, cmdProvision, "make devspace")
+	ex.assertBefore(t, cmdProvision, deploy)
+	ex.assertBefore(t, "make devspace", e2e)
+	ex.assertBefore(t, deploy, e2e)
+	ex.assertBefore(t, e2e, cp)
+}
+
+func TestRunnerDevspaceTestsFailed(t *testing.T) {
+	r, _, _ := newTestRunner(t, nil)
+	r.useDevspace = true
+	r.provisioned = true
+	r.readFile = func(name string) ([]byte, error) {
+		return []byte(`<testsuites failures="2"></testsuites>`), nil
+	}
+
+	err := runStages(context.Background(), r.stages())
+	var serr *stageError
+	assert.True(t, errors.As(err, &serr))
+	assert.Equal(t, stageExitCodes[stageTest], serr.ExitCode())
+}
+
+func TestRunnerFailedDeploy(t *testing.T) {
+	r, ex, closed := newTestRunner(t, &config.Devenv{})
+	r.provisioned = true
+	r.devconfigAfterDeploy = true
+	ex.errs = map[string]error{cmdBuild: errors.New("exit status 2")}
+
+	err := runStages(context.Background(), r.stages())
+	r.cleanup()
+	var serr *stageError
+	assert.True(t, errors.As(err, &serr))
+	assert.Equal(t, stageExitCodes[stageDeploy], serr.ExitCode())
+	assert.Equal(t, []string{cmdBuild}, ex.cmds)
+	assert.False(t, *closed, "no tunnel should have been opened")
+}
+
+func TestRunnerFailedDevconfigCancelsDeploy(t *testing.T) {
+	r, ex, _ := newTestRunner(t, &config.Devenv{Service: true})
+	r.provisioned = true
+	r.skipLocalizer = true
+	ex.errs = map[string]error{cmdDevconfig: errors.New("exit status 1")}
+	ex.blocking = map[string]bool{cmdDeploy: true}
+
+	// Without canceling the run, deploy would wait for the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := runStages(ctx, r.stages())
+	assert.NoError(t, ctx.Err(), "deploy should have been canceled when devconfig failed")
+
+	var serr *stageError
+	assert.True(t, errors.As(err, &serr))
+	assert.Equal(t, stageExitCodes[stageDevconfig], serr.ExitCode())
+	assert.NotContains(t, ex.cmds, cmdTest)
+}
+
+func TestPlanRun(t *testing.T) {
+	r, ex, _ := newTestRunner(t, &config.Devenv{Service: true})
+	p, err := planRun(context.Background(), r, r.stages())
+	assert.NoError(t, err)
+	assert.Empty(t, ex.cmds, "no command should be executed")
+
+	assert.Equal(t, []string{"mint"}, p.Dependencies)
+	assert.Equal(t, "base", p.ProvisionTarget)
+	assert.True(t, p.DestroyCluster)
+	assert.Equal(t, teardownOnSuccess, p.Teardown)
+	assert.Equal(t, stageProvision, p.Stages[1].Name)
+	assert.Equal(t, []*command{
+		newCommand("devenv", "--skip-update", "destroy"),
+		stdInOutErr("devenv", "--skip-update", "provision", "--snapshot-target", "base"),
+	}, p.Stages[1].Commands)
+}
@@ -0,0 +1,251 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the recorded state of e2e runs, used to resume a run from a stage.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// stateFile is where the state of the last e2e run is recorded
+const stateFile = "./bin/e2e-state.json"
+
+// Contains the reasons stages are skipped when resuming a run
+const (
+	// skipCompleted is the reason of stages before the resumed stage
+	skipCompleted = "completed in the recorded run"
+
+	// skipNotSelected is the reason of stages after the stage of --only
+	skipNotSelected = "not selected by --only"
+)
+
+// alwaysRun are the stages that are run even when resuming after them, as
+// what they do doesn't outlive a run
+var alwaysRun = []string{stageResolve, stageTunnel}
+
+// notResumable are the stages a run can't be resumed from: resuming needs
+// the devenv of the recorded run, which they'd provision anew
+var notResumable = []string{stageProvision, stageDockerBuild}
+
+// runState is the recorded state of an e2e run
+type runState struct {
+	// Cluster identifies the devenv the stages ran against, see clusterID
+	Cluster string `json:"cluster"`
+
+	// Dependencies are the resolved dependencies of the application,
+	// along with the ref they're pinned to, if any. It's nil when the run
+	// didn't resolve them, see runner.needsGraph.
+	Dependencies []string `json:"dependencies"`
+
+	// Completed are the names of the stages that completed, or were
+	// skipped, in the order they did
+	Completed []string `json:"completed"`
+
+	// Provisioned denotes if the devenv was provisioned by the run, which
+	// decides if it's torn down, see runner.effectiveTeardown
+	Provisioned bool `json:"provisioned,omitempty"`
+}
+
+// completed returns whether a stage completed
+func (s *runState) completed(name string) bool {
+	return slices.Contains(s.Completed, name)
+}
+
+// stateTracker records the stages of a run as they complete
+type stateTracker struct {
+	mu    sync.Mutex
+	r     *runner
+	state *runState
+}
+
+// loadState reads the recorded state of the last run
+func (r *runner) loadState() (*runState, error) {
+	b, err := os.ReadFile(r.statePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no e2e run was recorded in %s, run without --from or --only first", r.statePath)
+		}
+		return nil, errors.Wrap(err, "failed to read the recorded e2e run")
+	}
+
+	var s runState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", r.statePath)
+	}
+	return &s, nil
+}
+
+// clusterID returns the UID of the kube-system namespace of the devenv,
+// which changes when the devenv is re-created, or an empty string when
+// there's no devenv
+func clusterID(ctx context.Context, ex executor) string {
+	out, err := ex.Output(ctx,
+		newCommand("kubectl", "get", "namespace", "kube-system", "--output", "jsonpath={.metadata.uid}"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// dependencyState returns the dependencies of the application as recorded
+// in the state
+func (r *runner) dependencyState(ctx context.Context) ([]string, error) {
+	g, err := r.dg.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := make([]string, 0)
+	for _, n := range g.Nodes() {
+		if n.Name != g.Root {
+			deps = append(deps, config.Dependency{Name: n.Name, Ref: n.Ref}.String())
+		}
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
+
+// resume skips the stages before the named stage, and with only also the
+// stages after it, once it validated that the devenv, and the dependencies of
+// the application, still match the recorded run and that the skipped
+// stages completed in it. The stages in alwaysRun are never skipped
+// because of resuming, and those in notResumable can't be resumed from.
+func (r *runner) resume(ctx context.Context, stages []*stage, name string, only bool) ([]*stage, error) {
+	i := slices.IndexFunc(stages, func(s *stage) bool { return s.name == name })
+	if i == -1 {
+		names := make([]string, 0, len(stages))
+		for _, s := range stages {
+			names = append(names, s.name)
+		}
+		return nil, fmt.Errorf("unknown stage %q, expected one of %v", name, names)
+	}
+	if slices.Contains(notResumable, name) {
+		return nil, fmt.Errorf("can't resume from stage %s, the devenv of the recorded run is reused, it has to be run from the start", name)
+	}
+
+	state, err := r.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	if id := clusterID(ctx, r.ex); id == "" || id != state.Cluster {
+		return nil, errors.New("the devenv changed since the recorded run, it has to be run from the start")
+	}
+
+	// Dependencies are only compared when both runs resolve them
+	if state.Dependencies != nil && r.needsGraph() {
+		deps, err := r.dependencyState(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Equal(deps, state.Dependencies) {
+			return nil, fmt.Errorf("the dependencies changed since the recorded run, from %v to %v, it has to be run from the start",
+				state.Dependencies, deps)
+		}
+	}
+
+	// Stages from the resumed stage on are completed again
+	completed := make([]string, 0)
+	for j, s := range stages {
+		switch {
+		case slices.Contains(alwaysRun, s.name):
+		case j < i:
+			if !state.completed(s.name) {
+				return nil, fmt.Errorf("stage %s did not complete in the recorded run", s.name)
+			}
+			s.skip = skipCompleted
+			completed = append(completed, s.name)
+		case j > i && only:
+			s.skip = skipNotSelected
+		}
+	}
+	state.Completed = completed
+	r.state = state
+	r.created = state.Provisioned
+	return stages, nil
+}
+
+// track records the stages in the state file as they complete. Stages
+// skipped by the configuration of the run count as completed, those
+// skipped when resuming keep their recorded state. Unless the run was
+// resumed, the recorded state is reset first.
+func (r *runner) track(ctx context.Context, stages []*stage) {
+	if r.state == nil {
+		r.state = &runState{Completed: []string{}}
+	}
+	t := &stateTracker{r: r, state: r.state}
+
+	for _, s := range stages {
+		switch s.skip {
+		case skipCompleted, skipNotSelected:
+		case "":
+			name, run := s.name, s.run
+			s.run = func(ctx context.Context) error {
+				if name == stageProvision {
+					t.provisioned(ctx)
+				}
+				if err := run(ctx); err != nil {
+					return err
+				}
+				t.complete(ctx, name)
+				return nil
+			}
+		default:
+			t.state.Completed = append(t.state.Completed, s.name)
+		}
+	}
+	t.save(ctx)
+}
+
+// complete records that a stage completed
+func (t *stateTracker) complete(ctx context.Context, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if name == stageResolve {
+		if deps, err := t.r.dependencyState(ctx); err == nil {
+			t.state.Dependencies = deps
+		}
+	}
+	if !t.state.completed(name) {
+		t.state.Completed = append(t.state.Completed, name)
+	}
+	t.save(ctx)
+}
+
+// provisioned records that the devenv is provisioned by the run, once it
+// starts to, as a failed provision can leave a devenv behind too
+func (t *stateTracker) provisioned(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Provisioned = true
+	t.save(ctx)
+}
+
+// save writes the state to the state file, along with the devenv it's
+// for. Failing to do so only prevents resuming the run.
+func (t *stateTracker) save(ctx context.Context) {
+	t.state.Cluster = clusterID(ctx, t.r.ex)
+
+	b, err := json.MarshalIndent(t.state, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(t.r.statePath), 0o755); err == nil {
+			err = os.WriteFile(t.r.statePath, b, 0o600)
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to record the state of the e2e run")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// cmdClusterID is the command that returns the ID of the devenv
+const cmdClusterID = "kubectl get namespace kube-system --output jsonpath={.metadata.uid}"
+
+// newStateTestRunner returns a test runner, see newTestRunner, that
+// records its state in a temporary directory and whose devenv exists with
+// the given ID. The application pins a dependency, so that the
+// dependencies are resolved.
+func newStateTestRunner(t *testing.T, id string) (*runner, *fakeExecutor) {
+	r, ex, _ := newTestRunner(t, &config.Devenv{Service: true})
+	r.provisioned = true
+	r.dg.pinned = true
+	r.skipLocalizer = true
+	r.statePath = filepath.Join(t.TempDir(), "bin", "e2e-state.json")
+	ex.outputs = map[string][]byte{cmdClusterID: []byte(id + "\n")}
+	return r, ex
+}
+
+func TestTrack(t *testing.T) {
+	r, ex := newStateTestRunner(t, "abc")
+	ex.errs = map[string]error{cmdTest: errors.New("exit status 1")}
+	stages := r.stages()
+	r.track(context.Background(), stages)
+	assert.Error(t, runStages(context.Background(), stages))
+
+	s, err := r.loadState()
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", s.Cluster)
+	assert.Equal(t, []string{"mint"}, s.Dependencies)
+	assert.ElementsMatch(t, []string{
+		stageProvision, stageDockerBuild, stagePostDeploy, stageTunnel, stageResolve, stageDevconfig, stageDeploy,
+	}, s.Completed)
+}
+
+func TestResume(t *testing.T) {
+	r, _ := newStateTestRunner(t, "abc")
+	stages := r.stages()
+	r.track(context.Background(), stages)
+	assert.NoError(t, runStages(context.Background(), stages))
+
+	tests := []struct {
+		name    string
+		stage   string
+		only    bool
+		id      string
+		want    []string
+		wantErr string
+	}{
+		{name: "from", stage: stageDeploy, id: "abc", want: []string{cmdDeploy, cmdTest}},
+		{name: "only", stage: stageDeploy, only: true, id: "abc", want: []string{cmdDeploy}},
+		{name: "from test", stage: stageTest, id: "abc", want: []string{cmdTest}},
+		{name: "unknown stage", stage: "lint", id: "abc", wantErr: `unknown stage "lint"`},
+		{name: "from provision", stage: stageProvision, id: "abc", wantErr: "can't resume from stage provision"},
+		{name: "only docker-build", stage: stageDockerBuild, only: true, id: "abc", wantErr: "can't resume from stage docker-build"},
+		{name: "other devenv", stage: stageTest, id: "def", wantErr: "the devenv changed"},
+		{name: "no devenv", stage: stageTest, wantErr: "the devenv changed"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr, ex := newStateTestRunner(t, tc.id)
+			rr.statePath = r.statePath
+			if tc.id == "" {
+				ex.errs = map[string]error{cmdClusterID: errors.New("exit status 1")}
+			}
+
+			stages, err := rr.resume(context.Background(), rr.stages(), tc.stage, tc.only)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			ex.cmds = nil
+			assert.NoError(t, runStages(context.Background(), stages))
+			assert.Equal(t, tc.want, ex.cmds)
+		})
+	}
+}
+
+func TestResumeIncompleteRun(t *testing.T) {
+	r, ex := newStateTestRunner(t, "abc")
+	ex.errs = map[string]error{cmdDeploy: errors.New("exit status 1")}
+	stages := r.stages()
+	r.track(context.Background(), stages)
+	assert.Error(t, runStages(context.Background(), stages))
+
+	_, err := r.resume(context.Background(), r.stages(), stageTest, false)
+	assert.ErrorContains(t, err, "stage deploy did not complete in the recorded run")
+
+	r.dg.g.AddNode("flagship", "devenv.yaml")
+	_, err = r.resume(context.Background(), r.stages(), stageDeploy, false)
+	assert.ErrorContains(t, err, "the dependencies changed")
+}
+
+func TestResumeWithoutRecordedRun(t *testing.T) {
+	r, _ := newStateTestRunner(t, "abc")
+	_, err := r.resume(context.Background(), r.stages(), stageTest, false)
+	assert.ErrorContains(t, err, "no e2e run was recorded")
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the teardown of the devenv once an e2e run is done.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// teardownTimeout is how long destroying the devenv may take
+const teardownTimeout = 10 * time.Minute
+
+// teardownPolicy is when the devenv is destroyed once a run is done
+type teardownPolicy string
+
+// Contains the teardown policies
+const (
+	// teardownAlways destroys the devenv, even when the run failed
+	teardownAlways teardownPolicy = "always"
+
+	// teardownOnSuccess destroys the devenv when the run succeeded, a
+	// failed run leaves it to be debugged
+	teardownOnSuccess teardownPolicy = "on-success"
+
+	// teardownNever leaves the devenv
+	teardownNever teardownPolicy = "never"
+)
+
+// Validate returns an error if the policy isn't a known policy. An empty
+// policy is valid, see runner.effectiveTeardown for its default.
+func (p teardownPolicy) Validate() error {
+	switch p {
+	case "", teardownAlways, teardownOnSuccess, teardownNever:
+		return nil
+	default:
+		return fmt.Errorf("unknown teardown policy %q, expected one of [always on-success never]", p)
+	}
+}
+
+// effectiveTeardown returns the teardown policy of the run: the
+// configured one or otherwise, by default, on-success for a devenv the
+// runner created and never for an existing devenv it reused. In CI, where
+// the machine is thrown away anyways, the default is never.
+func (r *runner) effectiveTeardown() teardownPolicy {
+	switch {
+	case r.teardownPolicy != "":
+		return r.teardownPolicy
+	case !r.created, runningInCi():
+		return teardownNever
+	default:
+		return teardownOnSuccess
+	}
+}
+
+// teardown destroys the devenv according to the teardown policy of the
+// run. Failing to do so is only a warning.
+func (r *runner) teardown(success bool) {
+	p := r.effectiveTeardown()
+	if p == teardownNever || (p == teardownOnSuccess && !success) {
+		if r.created {
+			log.Info().Str("policy", string(p)).Msg("Leaving the devenv provisioned by this run, destroy it with `devenv destroy`")
+		}
+		return
+	}
+
+	// Not bound to the run, as an interrupted run is torn down too
+	ctx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+	defer cancel()
+
+	log.Info().Str("policy", string(p)).Msg("Destroying the devenv")
+	if err := r.ex.Run(ctx, stdOutErr("devenv", "--skip-update", "destroy")); err != nil {
+		log.Warn().Err(err).Msg("Failed to destroy the devenv")
+	}
+}
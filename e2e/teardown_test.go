@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeardown(t *testing.T) {
+	const cmdTeardown = "devenv --skip-update destroy"
+
+	tests := []struct {
+		name        string
+		provisioned bool
+		ci          bool
+		policy      teardownPolicy
+		fail        bool
+		want        bool
+	}{
+		{name: "created, succeeded", want: true},
+		{name: "created, failed", fail: true},
+		{name: "created, failed, always", fail: true, policy: teardownAlways, want: true},
+		{name: "created, succeeded, never", policy: teardownNever},
+		{name: "created in CI", ci: true},
+		{name: "created in CI, on-success", ci: true, policy: teardownOnSuccess, want: true},
+		{name: "reused", provisioned: true},
+		{name: "reused, always", provisioned: true, policy: teardownAlways, want: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, ex, _ := newTestRunner(t, &config.Devenv{})
+			r.skipLocalizer = true
+			r.provisioned = tc.provisioned
+			r.teardownPolicy = tc.policy
+			if tc.ci {
+				t.Setenv("CI", "true")
+			}
+			if tc.fail {
+				ex.errs = map[string]error{cmdTest: errors.New("exit status 1")}
+			}
+
+			err := runStages(context.Background(), r.stages())
+			assert.Equal(t, tc.fail, err != nil)
+			r.teardown(err == nil)
+
+			// Provisioning destroys the existing devenv first
+			destroys := 0
+			for _, c := range ex.cmds {
+				if c == cmdTeardown {
+					destroys++
+				}
+			}
+			if !tc.provisioned {
+				destroys--
+			}
+			assert.Equal(t, tc.want, destroys == 1, "commands: %q", ex.cmds)
+			if tc.want {
+				assert.Equal(t, cmdTeardown, ex.cmds[len(ex.cmds)-1])
+			}
+		})
+	}
+}
+
+func TestTeardownPolicyValidate(t *testing.T) {
+	assert.NoError(t, teardownPolicy("").Validate())
+	assert.NoError(t, teardownOnSuccess.Validate())
+	assert.ErrorContains(t, teardownPolicy("sometimes").Validate(), `unknown teardown policy "sometimes"`)
+}
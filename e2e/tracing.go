@@ -0,0 +1,94 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the tracing of the e2e runner, its stages and commands.
+
+package main
+
+import (
+	"context"
+
+	"github.com/getoutreach/devbase/v2/pkg/tracing"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startTracing starts tracing the e2e run, see tracing.Start, returning
+// the context of its span along with the function that ends it with the
+// exit code of the run
+func startTracing(ctx context.Context) (context.Context, func(code int)) {
+	shutdown, err := tracing.Start(ctx, "devbase-e2e")
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to start tracing, spans are not exported")
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "e2e")
+	return ctx, func(code int) {
+		span.SetAttributes(tracing.AttrExitCode.Int(code))
+		span.End()
+		if shutdown == nil {
+			return
+		}
+		if err := shutdown(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("Failed to export spans")
+		}
+	}
+}
+
+// runStage runs a stage in a span, a skipped stage gets an empty span
+func runStage(ctx context.Context, s *stage) error {
+	ctx, span := tracing.Tracer().Start(ctx, s.name, trace.WithAttributes(tracing.AttrStage.String(s.name)))
+	if s.skip != "" {
+		span.SetAttributes(tracing.AttrStageSkip.String(s.skip))
+		span.End()
+		return nil
+	}
+
+	err := s.run(ctx)
+	if err != nil {
+		span.SetAttributes(tracing.AttrExitCode.Int((&stageError{stage: s.name, err: err}).ExitCode()))
+	}
+	tracing.End(span, err)
+	return err
+}
+
+// tracedExecutor runs the commands of an executor in spans
+type tracedExecutor struct {
+	ex executor
+}
+
+// start starts the span of a command
+func (tracedExecutor) start(ctx context.Context, c *command) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, c.Name, trace.WithAttributes(tracing.AttrCommand.String(c.String())))
+}
+
+// end ends the span of a command, see tracing.End
+func (tracedExecutor) end(span trace.Span, err error) {
+	if err == nil {
+		span.SetAttributes(tracing.AttrExitCode.Int(0))
+	}
+	tracing.End(span, err)
+}
+
+// Run implements executor
+func (e tracedExecutor) Run(ctx context.Context, c *command) error {
+	ctx, span := e.start(ctx, c)
+	err := e.ex.Run(ctx, c)
+	e.end(span, err)
+	return err
+}
+
+// Output implements executor
+func (e tracedExecutor) Output(ctx context.Context, c *command) ([]byte, error) {
+	ctx, span := e.start(ctx, c)
+	out, err := e.ex.Output(ctx, c)
+	e.end(span, err)
+	return out, err
+}
+
+// Start implements executor. The span only covers starting the command.
+func (e tracedExecutor) Start(ctx context.Context, c *command) error {
+	ctx, span := e.start(ctx, c)
+	err := e.ex.Start(ctx, c)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans records the spans ended during the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+// spanAttributes returns the attributes of the spans by span name
+func spanAttributes(sr *tracetest.SpanRecorder) map[string]map[attribute.Key]attribute.Value {
+	attrs := make(map[string]map[attribute.Key]attribute.Value)
+	for _, s := range sr.Ended() {
+		attrs[s.Name()] = make(map[attribute.Key]attribute.Value)
+		for _, kv := range s.Attributes() {
+			attrs[s.Name()][kv.Key] = kv.Value
+		}
+	}
+	return attrs
+}
+
+func TestTracing(t *testing.T) {
+	sr := recordSpans(t)
+	r, ex, _ := newTestRunner(t, &config.Devenv{})
+	r.ex = tracedExecutor{ex: ex}
+	r.dg.ex = r.ex
+	r.provisioned = true
+	r.dg.pinned = true
+	r.skipLocalizer = true
+	r.devconfigAfterDeploy = true
+	ex.errs = map[string]error{cmdTest: errors.New("exit status 1")}
+
+	assert.Error(t, runStages(context.Background(), r.stages()))
+
+	attrs := spanAttributes(sr)
+	assert.Equal(t, int64(1), attrs[stageResolve][tracing.AttrDepsCount].AsInt64())
+	assert.Equal(t, "the devenv already exists", attrs[stageProvision][tracing.AttrStageSkip].AsString())
+	assert.Equal(t, cmdBuild, attrs["make"][tracing.AttrCommand].AsString())
+	assert.Equal(t, int64(0), attrs["make"][tracing.AttrExitCode].AsInt64())
+	assert.Equal(t, int64(stageExitCodes[stageTest]), attrs[stageTest][tracing.AttrExitCode].AsInt64())
+
+	// Commands are children of the span of their stage
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		spans[s.Name()] = s
+	}
+	assert.Equal(t, spans[stageDeploy].SpanContext().SpanID(), spans["make"].Parent().SpanID())
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the forge provider abstraction.
+
+// Package forge contains providers for reading files from repositories
+// hosted on a forge, e.g. GitHub or GitLab, at a git ref.
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	giturls "github.com/chainguard-dev/git-urls"
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by a Provider when the requested repository,
+// ref or file does not exist
+var ErrNotFound = errors.New("not found")
+
+// Contains the kinds of providers
+const (
+	// KindGitHub is github.com
+	KindGitHub = "github"
+
+	// KindGitHubEnterprise is a GitHub Enterprise Server, at a base URL
+	KindGitHubEnterprise = "github-enterprise"
+
+	// KindGitLab is gitlab.com, or a self-hosted GitLab at a base URL
+	KindGitLab = "gitlab"
+
+	// KindGit is any git remote, e.g. file:// or ssh://, at a base URL
+	KindGit = "git"
+)
+
+// Provider reads files from repositories hosted on a forge. Repositories
+// are identified by their full path, e.g. "getoutreach/devbase" or, for
+// GitLab subgroups, "group/subgroup/project".
+type Provider interface {
+	// Name returns the kind of the provider, e.g. "github"
+	Name() string
+
+	// ReadFile returns the contents of file in the repository at the given
+	// git ref, or the default branch when ref is empty. ErrNotFound is
+	// returned if the repository, ref or file doesn't exist.
+	ReadFile(ctx context.Context, repo, file, ref string) ([]byte, error)
+
+	// CloneURL returns the URL the repository can be cloned from
+	CloneURL(repo string) string
+}
+
+// New creates a provider of the given kind. baseURL is required for
+// KindGitHubEnterprise and KindGit, and optional for KindGitLab.
+func New(kind, baseURL string) (Provider, error) {
+	switch kind {
+	case "", KindGitHub:
+		return NewGitHub(nil)
+	case KindGitHubEnterprise:
+		if baseURL == "" {
+			return nil, fmt.Errorf("a base URL is required for %s", kind)
+		}
+		return NewGitHubEnterprise(baseURL)
+	case KindGitLab:
+		return NewGitLab(baseURL, nil), nil
+	case KindGit:
+		if baseURL == "" {
+			return nil, fmt.Errorf("a base URL is required for %s", kind)
+		}
+		return NewGit(baseURL), nil
+	default:
+		return nil, fmt.Errorf("unknown forge %q, expected one of [%s %s %s %s]",
+			kind, KindGitHub, KindGitHubEnterprise, KindGitLab, KindGit)
+	}
+}
+
+// CloneURL returns the URL the repository can be cloned from on the forge
+// of the given kind and base URL, see New. Unlike New, no provider is
+// created, so no credentials of the forge are needed.
+func CloneURL(kind, baseURL, repo string) (string, error) {
+	switch kind {
+	case "", KindGitHub:
+		return (&GitHub{host: "github.com"}).CloneURL(repo), nil
+	case KindGitHubEnterprise:
+		if baseURL == "" {
+			return "", fmt.Errorf("a base URL is required for %s", kind)
+		}
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid GitHub Enterprise URL %q", baseURL)
+		}
+		return (&GitHub{host: u.Host}).CloneURL(repo), nil
+	case KindGitLab:
+		return NewGitLab(baseURL, nil).CloneURL(repo), nil
+	case KindGit:
+		if baseURL == "" {
+			return "", fmt.Errorf("a base URL is required for %s", kind)
+		}
+		return NewGit(baseURL).CloneURL(repo), nil
+	default:
+		return "", fmt.Errorf("unknown forge %q, expected one of [%s %s %s %s]",
+			kind, KindGitHub, KindGitHubEnterprise, KindGitLab, KindGit)
+	}
+}
+
+// Remote is a parsed git remote URL
+type Remote struct {
+	// Scheme is the scheme of the URL, e.g. https, ssh or file
+	Scheme string
+
+	// Host is the host of the forge, empty for file remotes
+	Host string
+
+	// Path is the full path of the repository, without a .git suffix,
+	// e.g. "getoutreach/devbase" or "group/subgroup/project"
+	Path string
+}
+
+// ParseRemote parses a git remote URL in any of the forms git accepts,
+// e.g. git@github.com:getoutreach/devbase.git,
+// https://gitlab.com/group/subgroup/project or file:///srv/git/org/repo.git
+func ParseRemote(remote string) (*Remote, error) {
+	var u *url.URL
+	var err error
+	if strings.HasPrefix(remote, "https://") || strings.HasPrefix(remote, "http://") {
+		u, err = url.Parse(remote)
+	} else {
+		u, err = giturls.Parse(remote)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse git remote %q", remote)
+	}
+
+	p := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if p == "" {
+		return nil, fmt.Errorf("git remote %q has no repository path", remote)
+	}
+	return &Remote{Scheme: u.Scheme, Host: u.Hostname(), Path: p}, nil
+}
+
+// Org returns the organization of the repository: everything but the
+// name of the repository, e.g. "group/subgroup" for GitLab subgroups. For
+// file remotes, which contain a filesystem path, only the parent
+// directory of the repository is used.
+func (r *Remote) Org() string {
+	dir := path.Dir(r.Path)
+	if dir == "." {
+		return ""
+	}
+	if r.Scheme == "file" {
+		return path.Base(dir)
+	}
+	return dir
+}
+
+// Name returns the name of the repository
+func (r *Remote) Name() string {
+	return path.Base(r.Path)
+}
@@ -0,0 +1,126 @@
+package forge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		remote string
+		host   string
+		path   string
+		org    string
+	}{
+		{remote: "git@github.com:getoutreach/devbase.git", host: "github.com", path: "getoutreach/devbase", org: "getoutreach"},
+		{remote: "https://github.com/getoutreach/devbase", host: "github.com", path: "getoutreach/devbase", org: "getoutreach"},
+		{remote: "https://github.example.com/team/app.git", host: "github.example.com", path: "team/app", org: "team"},
+		{remote: "git@gitlab.com:group/sub/project.git", host: "gitlab.com", path: "group/sub/project", org: "group/sub"},
+		{remote: "ssh://git@gitlab.example.com:2222/group/project", host: "gitlab.example.com", path: "group/project", org: "group"},
+		{remote: "file:///srv/git/team/app.git", path: "srv/git/team/app", org: "team"},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRemote(tt.remote)
+		if assert.NoError(t, err, tt.remote) {
+			assert.Equal(t, tt.host, r.Host, tt.remote)
+			assert.Equal(t, tt.path, r.Path, tt.remote)
+			assert.Equal(t, tt.org, r.Org(), tt.remote)
+		}
+	}
+}
+
+func TestCloneURL(t *testing.T) {
+	tests := []struct {
+		kind, baseURL, want string
+	}{
+		{want: "git@github.com:org/repo.git"},
+		{kind: KindGitHubEnterprise, baseURL: "https://github.example.com", want: "git@github.example.com:org/repo.git"},
+		{kind: KindGitLab, want: "git@gitlab.com:org/repo.git"},
+		{kind: KindGit, baseURL: "file:///srv/git/", want: "file:///srv/git/org/repo.git"},
+	}
+	for _, tc := range tests {
+		got, err := CloneURL(tc.kind, tc.baseURL, "org/repo")
+		assert.NoError(t, err, tc.kind)
+		assert.Equal(t, tc.want, got, tc.kind)
+	}
+
+	_, err := CloneURL(KindGit, "", "org/repo")
+	assert.Error(t, err)
+	_, err = CloneURL("svn", "", "org/repo")
+	assert.Error(t, err)
+}
+
+func TestGitLab(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("PRIVATE-TOKEN"))
+		switch r.URL.RawPath + "?" + r.URL.RawQuery {
+		case "/api/v4/projects/group%2Fsub%2Fproject/repository/files/devenv.yaml/raw?ref=v1":
+			_, _ = w.Write([]byte("service: true\n"))
+		case "/api/v4/projects/group%2Fsub%2Fproject/repository/commits/HEAD?":
+			_, _ = w.Write([]byte(`{"id": "abc123"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("GITLAB_TOKEN", "secret")
+	p := NewGitLab(srv.URL, srv.Client())
+	ctx := context.Background()
+
+	b, err := p.ReadFile(ctx, "group/sub/project", "devenv.yaml", "v1")
+	assert.NoError(t, err)
+	assert.Equal(t, "service: true\n", string(b))
+
+	_, err = p.ReadFile(ctx, "group/sub/project", "service.yaml", "v1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	rev, err := p.Revision(ctx, "group/sub/project", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", rev)
+}
+
+func TestGit(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "team", "app.git")
+	work := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet", "--bare", repo},
+		{"-C", work, "init", "--quiet"},
+		{"-C", work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "init"},
+	} {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(work, "devenv.yaml"), []byte("service: true\n"), 0o600))
+	for _, args := range [][]string{
+		{"-C", work, "add", "devenv.yaml"},
+		{"-C", work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "add"},
+		{"-C", work, "tag", "v1"},
+		{"-C", work, "push", "--quiet", repo, "HEAD:refs/heads/main", "v1"},
+	} {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+
+	p := NewGit("file://" + root)
+	ctx := context.Background()
+
+	b, err := p.ReadFile(ctx, "team/app", "devenv.yaml", "v1")
+	assert.NoError(t, err)
+	assert.Equal(t, "service: true\n", string(b))
+
+	_, err = p.ReadFile(ctx, "team/app", "service.yaml", "v1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = p.ReadFile(ctx, "team/missing", "devenv.yaml", "")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the plain git provider.
+
+package forge
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Git reads files from repositories on any git remote, e.g. file:// or
+// ssh://, with `git archive`. Remotes that don't support `git archive`,
+// like most HTTPS remotes, are read from a shallow bare clone instead.
+type Git struct {
+	baseURL string
+}
+
+// NewGit creates a provider for the repositories under the given base
+// URL, e.g. file:///srv/git, which are cloned from <baseURL>/<repo>.git
+func NewGit(baseURL string) *Git {
+	return &Git{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Name implements Provider
+func (*Git) Name() string {
+	return KindGit
+}
+
+// ReadFile implements Provider
+func (p *Git) ReadFile(ctx context.Context, repo, file, ref string) ([]byte, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	//nolint:gosec // Why: the ref and file can't be an option after --end-of-options.
+	out, err := exec.CommandContext(ctx, "git", "archive", "--format=tar", "--remote="+p.CloneURL(repo),
+		"--end-of-options", ref, file).Output()
+	if err == nil {
+		return readTarFile(out, file)
+	}
+
+	return p.readFromClone(ctx, repo, file, ref)
+}
+
+// readFromClone reads a file from a shallow bare clone of the repository
+// at the given ref
+func (p *Git) readFromClone(ctx context.Context, repo, file, ref string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "devbase-forge-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	git := func(args ...string) ([]byte, error) {
+		//nolint:gosec // Why: callers pass refs after --end-of-options, so they can't be an option.
+		return exec.CommandContext(ctx, "git", append([]string{"--git-dir", dir}, args...)...).Output()
+	}
+
+	if _, err := git("init", "--quiet", "--bare"); err != nil {
+		return nil, errors.Wrap(err, "failed to create bare repository")
+	}
+	if _, err := git("fetch", "--quiet", "--depth", "1", "--end-of-options", p.CloneURL(repo), ref); err != nil {
+		// git doesn't distinguish between a missing repository and a missing ref
+		return nil, ErrNotFound
+	}
+
+	b, err := git("show", "--end-of-options", "FETCH_HEAD:"+file)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return b, nil
+}
+
+// readTarFile returns the contents of file in a tar archive
+func readTarFile(b []byte, file string) ([]byte, error) {
+	tr := tar.NewReader(bytes.NewReader(b))
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read git archive")
+		}
+		if h.Name == file && h.Typeflag == tar.TypeReg {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// CloneURL implements Provider
+func (p *Git) CloneURL(repo string) string {
+	return p.baseURL + "/" + repo + ".git"
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the GitHub provider.
+
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	githubauth "github.com/getoutreach/gobox/pkg/cli/github"
+	"github.com/google/go-github/v58/github"
+	"github.com/pkg/errors"
+)
+
+// GitHub reads files from repositories on github.com, or on a GitHub
+// Enterprise Server
+type GitHub struct {
+	gh   *github.Client
+	kind string
+	host string
+}
+
+// NewGitHub creates a provider for github.com using the given client, or
+// when nil a client authenticated as the current user
+func NewGitHub(gh *github.Client) (*GitHub, error) {
+	if gh == nil {
+		var err error
+		if gh, err = githubauth.NewClient(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &GitHub{gh: gh, kind: KindGitHub, host: "github.com"}, nil
+}
+
+// NewGitHubEnterprise creates a provider for the GitHub Enterprise Server
+// at the given base URL, e.g. https://github.example.com, authenticated as
+// the current user
+func NewGitHubEnterprise(baseURL string) (*GitHub, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid GitHub Enterprise URL %q", baseURL)
+	}
+
+	gh, err := githubauth.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	if gh, err = gh.WithEnterpriseURLs(baseURL, baseURL); err != nil {
+		return nil, errors.Wrapf(err, "invalid GitHub Enterprise URL %q", baseURL)
+	}
+
+	return &GitHub{gh: gh, kind: KindGitHubEnterprise, host: u.Host}, nil
+}
+
+// Name implements Provider
+func (p *GitHub) Name() string {
+	return p.kind
+}
+
+// ReadFile implements Provider
+func (p *GitHub) ReadFile(ctx context.Context, repo, file, ref string) ([]byte, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid GitHub repository %q, expected owner/name", repo)
+	}
+
+	fc, _, resp, err := p.gh.Repositories.GetContents(ctx, owner, name, file,
+		&github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	// A directory was found at the path, which isn't what we're looking for
+	if fc == nil {
+		return nil, ErrNotFound
+	}
+
+	content, err := fc.GetContent()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode file contents")
+	}
+	return []byte(content), nil
+}
+
+// Revision returns the commit SHA of the given ref, or the default branch
+// when empty. When the SHA is still lastKnown, GitHub doesn't transfer it.
+func (p *GitHub) Revision(ctx context.Context, repo, ref, lastKnown string) (string, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return "", fmt.Errorf("invalid GitHub repository %q, expected owner/name", repo)
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	sha, resp, err := p.gh.Repositories.GetCommitSHA1(ctx, owner, name, ref, lastKnown)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return lastKnown, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// CloneURL implements Provider
+func (p *GitHub) CloneURL(repo string) string {
+	return fmt.Sprintf("git@%s:%s.git", p.host, repo)
+}
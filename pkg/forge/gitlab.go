@@ -0,0 +1,115 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the GitLab provider.
+
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultGitLabURL is the base URL of gitlab.com
+const DefaultGitLabURL = "https://gitlab.com"
+
+// GitLab reads files from projects on gitlab.com, or on a self-hosted
+// GitLab, including projects in subgroups. Requests are authenticated
+// with the token in GITLAB_TOKEN, if set.
+type GitLab struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewGitLab creates a provider for the GitLab at the given base URL,
+// gitlab.com when empty, using the given HTTP client, or when nil
+// http.DefaultClient
+func NewGitLab(baseURL string, client *http.Client) *GitLab {
+	if baseURL == "" {
+		baseURL = DefaultGitLabURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &GitLab{baseURL: strings.TrimSuffix(baseURL, "/"), token: os.Getenv("GITLAB_TOKEN"), client: client}
+}
+
+// Name implements Provider
+func (*GitLab) Name() string {
+	return KindGitLab
+}
+
+// ReadFile implements Provider
+func (p *GitLab) ReadFile(ctx context.Context, repo, file, ref string) ([]byte, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	return p.get(ctx, fmt.Sprintf("/repository/files/%s/raw?ref=%s", url.PathEscape(file), url.QueryEscape(ref)), repo)
+}
+
+// Revision returns the commit SHA of the given ref, or the default branch
+// when empty
+func (p *GitLab) Revision(ctx context.Context, repo, ref, _ string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	b, err := p.get(ctx, "/repository/commits/"+url.PathEscape(ref), repo)
+	if err != nil {
+		return "", err
+	}
+
+	var commit struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(b, &commit); err != nil {
+		return "", errors.Wrap(err, "failed to parse commit")
+	}
+	return commit.ID, nil
+}
+
+// get returns the body of a request to the API of a project, the full
+// path of the project, including subgroups, is escaped as GitLab expects
+func (p *GitLab) get(ctx context.Context, endpoint, repo string) ([]byte, error) {
+	u := fmt.Sprintf("%s/api/v4/projects/%s%s", p.baseURL, url.PathEscape(repo), endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	if p.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("GitLab returned %s for %s", resp.Status, repo)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// CloneURL implements Provider
+func (p *GitLab) CloneURL(repo string) string {
+	u, err := url.Parse(p.baseURL)
+	if err != nil {
+		return p.baseURL + "/" + repo + ".git"
+	}
+	return fmt.Sprintf("git@%s:%s.git", u.Host, repo)
+}
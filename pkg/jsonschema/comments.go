@@ -0,0 +1,127 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the extraction of doc comments from Go source.
+
+package jsonschema
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Comments are the doc comments of types, keyed by "<pkg path>.<type>",
+// and of their fields, keyed by "<pkg path>.<type>.<field>"
+type Comments map[string]string
+
+// key returns the key of the doc comment of a type, or of one of its
+// fields when field isn't empty
+func key(t reflect.Type, field string) string {
+	k := t.PkgPath() + "." + t.Name()
+	if field != "" {
+		k += "." + field
+	}
+	return k
+}
+
+// LoadComments reads the doc comments of the types, and their fields, of
+// the Go package with the given import path from its source in dir.
+// Tests are ignored.
+func LoadComments(comments Comments, dir, pkgPath string) error {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", dir)
+	}
+
+	for name, pkg := range pkgs {
+		if strings.HasSuffix(name, "_test") {
+			continue
+		}
+		for _, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					addTypeComments(comments, pkgPath, gd, spec.(*ast.TypeSpec))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addTypeComments adds the doc comments of a type, and its fields
+func addTypeComments(comments Comments, pkgPath string, gd *ast.GenDecl, ts *ast.TypeSpec) {
+	typeKey := pkgPath + "." + ts.Name.Name
+
+	// The doc comment of a type is on the declaration, unless it's part
+	// of a group of type declarations
+	doc := ts.Doc
+	if doc == nil && len(gd.Specs) == 1 {
+		doc = gd.Doc
+	}
+	if text := unwrap(doc.Text()); text != "" {
+		comments[typeKey] = text
+	}
+
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return
+	}
+	for _, field := range st.Fields.List {
+		text := unwrap(field.Doc.Text())
+		if text == "" {
+			continue
+		}
+		for _, name := range field.Names {
+			comments[typeKey+"."+name.Name] = text
+		}
+		// Embedded fields are named after their type
+		if len(field.Names) == 0 {
+			if ident := embeddedName(field.Type); ident != "" {
+				comments[typeKey+"."+ident] = text
+			}
+		}
+	}
+}
+
+// embeddedName returns the name of an embedded field of the given type
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	default:
+		return ""
+	}
+}
+
+// unwrap joins the wrapped lines of the paragraphs of a doc comment, so
+// that editors can wrap them instead. Indented lines, e.g. examples, are
+// kept as they are.
+func unwrap(text string) string {
+	var b strings.Builder
+	prev := ""
+	for i, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		switch {
+		case i == 0:
+		case line == "" || prev == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(prev, "\t"):
+			b.WriteString("\n")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(line)
+		prev = line
+	}
+	return b.String()
+}
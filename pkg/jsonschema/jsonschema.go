@@ -0,0 +1,181 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the generation of JSON Schemas from Go types.
+
+// Package jsonschema generates JSON Schemas (draft-07) of YAML
+// configuration files from the Go types they're decoded into, so that
+// editors can autocomplete and validate them, e.g. through
+// yaml-language-server.
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Draft07 is the URI of the JSON Schema version that's generated
+const Draft07 = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a JSON Schema
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	If                   *Schema            `json:"if,omitempty"`
+	Then                 *Schema            `json:"then,omitempty"`
+	Else                 *Schema            `json:"else,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// Generator generates the schemas of Go types. Named types are added to
+// the definitions of the Generator and referenced, so that they're only
+// described once.
+type Generator struct {
+	// Comments are the doc comments of types and their fields, used as
+	// the descriptions of the schemas, see LoadComments
+	Comments Comments
+
+	// Overrides change the schema generated for a type, e.g. to add the
+	// string form of a type that implements yaml.Unmarshaler
+	Overrides map[reflect.Type]func(s *Schema) *Schema
+
+	// definitions are the schemas of the named types seen so far
+	definitions map[string]*Schema
+
+	// names are the definition names of the named types seen so far
+	names map[reflect.Type]string
+}
+
+// NewGenerator creates a Generator using the given doc comments
+func NewGenerator(comments Comments) *Generator {
+	return &Generator{
+		Comments:    comments,
+		Overrides:   make(map[reflect.Type]func(s *Schema) *Schema),
+		definitions: make(map[string]*Schema),
+		names:       make(map[reflect.Type]string),
+	}
+}
+
+// Reflect returns the schema of the type of v, which refers to the
+// definitions of the Generator
+func (g *Generator) Reflect(v interface{}) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+// Definitions returns the schemas of all named types that were referred
+// to, by name
+func (g *Generator) Definitions() map[string]*Schema {
+	return g.definitions
+}
+
+// schema returns the schema of a type
+func (g *Generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Named types are defined once, and referred to
+	if t.Name() != "" && t.PkgPath() != "" {
+		return &Schema{Ref: "#/definitions/" + g.define(t)}
+	}
+	return g.typeSchema(t)
+}
+
+// define adds the schema of a named type to the definitions, unless it's
+// already there, and returns its name
+func (g *Generator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, ok := g.definitions[name]; ok {
+		// Another type with the same name, qualify it with its package
+		name = t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + "." + name
+	}
+	g.names[t] = name
+
+	// Reserve the name before generating the schema, so that recursive
+	// types refer to it
+	g.definitions[name] = &Schema{}
+	s := g.typeSchema(t)
+	if s.Description == "" {
+		s.Description = g.Comments[key(t, "")]
+	}
+	if o, ok := g.Overrides[t]; ok {
+		s = o(s)
+	}
+	g.definitions[name] = s
+	return name
+}
+
+// typeSchema returns the schema of a type, without referring to it
+func (g *Generator) typeSchema(t reflect.Type) *Schema {
+	switch t.Kind() { //nolint:exhaustive // Why: other kinds can't be decoded from YAML
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		g.addFields(s, t)
+		return s
+	default:
+		// interface{}, anything goes
+		return &Schema{}
+	}
+}
+
+// addFields adds the fields of a struct type to the properties of an
+// object schema, following the yaml tags of the fields
+func (g *Generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		// Like yaml, embedded structs are inlined even when unexported
+		if (!f.IsExported() && !f.Anonymous) || name == "-" {
+			continue
+		}
+
+		if strings.Contains(opts, "inline") {
+			switch f.Type.Kind() { //nolint:exhaustive // Why: only structs and maps can be inlined
+			case reflect.Struct:
+				g.addFields(s, f.Type)
+			case reflect.Map:
+				s.AdditionalProperties = g.schema(f.Type.Elem())
+			default:
+				panic(fmt.Sprintf("%s.%s: can't inline a %s", t, f.Name, f.Type.Kind()))
+			}
+			continue
+		}
+
+		if name == "" {
+			// The default key of yaml
+			name = strings.ToLower(f.Name)
+		}
+		p := g.schema(f.Type)
+		p.Description = g.Comments[key(t, f.Name)]
+		s.Properties[name] = p
+	}
+}
@@ -0,0 +1,62 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testConfig is a configuration
+type testConfig struct {
+	// Name is a name
+	Name string `yaml:"name"`
+
+	// Items are items
+	Items []testItem `yaml:"items,omitempty"`
+
+	Skipped string `yaml:"-"`
+
+	testInline `yaml:",inline"`
+}
+
+type testItem struct {
+	Next  *testItem              `yaml:"next,omitempty"`
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+type testInline struct {
+	Enabled bool `yaml:"enabled"`
+	Count   int
+}
+
+func TestGenerator(t *testing.T) {
+	comments := make(Comments)
+	assert.NoError(t, LoadComments(comments, ".", "github.com/getoutreach/devbase/v2/pkg/jsonschema"))
+	assert.Equal(t, "Name is a name", comments["github.com/getoutreach/devbase/v2/pkg/jsonschema.testConfig.Name"])
+
+	g := NewGenerator(comments)
+	g.Overrides[reflect.TypeOf(testItem{})] = func(s *Schema) *Schema {
+		return &Schema{OneOf: []*Schema{{Type: "string"}, s}}
+	}
+
+	assert.Equal(t, &Schema{Ref: "#/definitions/testConfig"}, g.Reflect(testConfig{}))
+	assert.Equal(t, map[string]*Schema{
+		"testConfig": {
+			Type:        "object",
+			Description: "testConfig is a configuration",
+			Properties: map[string]*Schema{
+				"name":    {Type: "string", Description: "Name is a name"},
+				"items":   {Type: "array", Items: &Schema{Ref: "#/definitions/testItem"}, Description: "Items are items"},
+				"enabled": {Type: "boolean"},
+				"count":   {Type: "integer"},
+			},
+			AdditionalProperties: false,
+		},
+		"testItem": {OneOf: []*Schema{{Type: "string"}, {
+			Type:                 "object",
+			Properties:           map[string]*Schema{"next": {Ref: "#/definitions/testItem"}},
+			AdditionalProperties: &Schema{},
+		}}},
+	}, g.Definitions())
+}
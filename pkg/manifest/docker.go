@@ -0,0 +1,71 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the typed model of deployments/docker.yaml.
+
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// DockerFile is the path, relative to the root of a repository, of the
+// file Docker is read from
+const DockerFile = "deployments/docker.yaml"
+
+// Docker configures the Docker images built from a repository,
+// deployments/docker.yaml. It maps the name of every image, which is the
+// name of its directory in deployments/, to its configuration. An image
+// with the name of the application is built from the root of the
+// repository.
+type Docker map[string]Image
+
+// Image configures how a Docker image is built and pushed
+type Image struct {
+	// BuildContext is the build context of the image. Defaults to "." for
+	// the image named after the application, and to
+	// ./deployments/<image> otherwise.
+	BuildContext string `yaml:"buildContext,omitempty"`
+
+	// PushTo is the image, including registry, the image is pushed to.
+	// Defaults to <box devenv.imageRegistry>/<image>, or
+	// <box devenv.imageRegistry>/<application>/<image> for images not
+	// named after the application.
+	PushTo string `yaml:"pushTo,omitempty"`
+
+	// Secrets are the secrets exposed to the build, in the format of
+	// `docker buildx build --secret`, e.g. id=mySecret,env=MY_SECRET.
+	// Defaults to id=npmtoken,env=NPM_TOKEN.
+	Secrets []string `yaml:"secrets,omitempty"`
+
+	// Platforms are the platforms the image is built for, e.g.
+	// linux/amd64. Defaults to linux/arm64 and linux/amd64.
+	Platforms []string `yaml:"platforms,omitempty"`
+}
+
+// LoadDocker finds the root of the repository dir is in, see FindRoot,
+// and loads its deployments/docker.yaml
+func LoadDocker(dir string) (Docker, error) {
+	root, err := FindRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(filepath.Join(root, DockerFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", DockerFile)
+	}
+	return ParseDocker(b)
+}
+
+// ParseDocker parses the contents of a deployments/docker.yaml
+func ParseDocker(b []byte) (Docker, error) {
+	d := make(Docker)
+	if err := yaml.Unmarshal(b, &d); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", DockerFile)
+	}
+	return d, nil
+}
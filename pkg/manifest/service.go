@@ -0,0 +1,162 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the typed model of service.yaml.
+
+// Package manifest contains typed models of the files that describe a
+// repository, e.g. service.yaml.
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ServiceFile is the name of the file Service is read from
+const ServiceFile = "service.yaml"
+
+// Service is the configuration of a repository for stencil, service.yaml
+type Service struct {
+	// Dir is the root directory of the repository the configuration was
+	// loaded from, empty when it was parsed from bytes
+	Dir string `yaml:"-"`
+
+	// Name is the name of the application, which is the name of the
+	// repository
+	Name string `yaml:"name"`
+
+	// Arguments are the arguments passed to the stencil modules
+	Arguments Arguments `yaml:"arguments"`
+
+	// Modules are the stencil modules used by the repository
+	Modules []Module `yaml:"modules"`
+
+	// Replacements maps stencil module names to local paths or URLs
+	Replacements map[string]string `yaml:"replacements,omitempty"`
+}
+
+// Arguments are the arguments passed to the stencil modules. Arguments
+// without a field are kept in Other.
+type Arguments struct {
+	// Description is a short description of the application
+	Description string `yaml:"description,omitempty"`
+
+	// ReportingTeam is the team that owns the application
+	ReportingTeam string `yaml:"reportingTeam,omitempty"`
+
+	// CIOptions configures CI
+	CIOptions CIOptions `yaml:"ciOptions,omitempty"`
+
+	// ReleaseOptions configures how the application is released
+	ReleaseOptions ReleaseOptions `yaml:"releaseOptions,omitempty"`
+
+	// VaultSecrets are the Vault paths of the secrets the application
+	// needs, which may contain %(environment)s
+	VaultSecrets []string `yaml:"vaultSecrets,omitempty"`
+
+	// Other are the arguments without a field
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+// CIOptions configures CI. Options without a field are kept in Other.
+type CIOptions struct {
+	// SkipE2e disables running the e2e tests in CI
+	SkipE2e bool `yaml:"skipE2e,omitempty"`
+
+	// SkipDocker disables building the docker image in CI
+	SkipDocker bool `yaml:"skipDocker,omitempty"`
+
+	// Other are the options without a field
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+// ReleaseOptions configures how the application is released. Options
+// without a field are kept in Other.
+type ReleaseOptions struct {
+	// AllowMajorVersions allows releasing breaking changes as new major versions
+	AllowMajorVersions bool `yaml:"allowMajorVersions,omitempty"`
+
+	// EnablePrereleases enables releasing prereleases from PrereleasesBranch
+	EnablePrereleases bool `yaml:"enablePrereleases,omitempty"`
+
+	// AutoPrereleases releases a prerelease on every change of PrereleasesBranch
+	AutoPrereleases bool `yaml:"autoPrereleases,omitempty"`
+
+	// PrereleasesBranch is the branch prereleases are released from
+	PrereleasesBranch string `yaml:"prereleasesBranch,omitempty"`
+
+	// Other are the options without a field
+	Other map[string]interface{} `yaml:",inline"`
+}
+
+// Module is a stencil module used by a repository
+type Module struct {
+	// Name is the import path of the module
+	Name string `yaml:"name"`
+
+	// URL is the URL the module is fetched from, when not its import path
+	URL string `yaml:"url,omitempty"`
+
+	// Channel is the release channel the module is taken from, e.g. unstable
+	Channel string `yaml:"channel,omitempty"`
+
+	// Version is the version of the module, when pinned
+	Version string `yaml:"version,omitempty"`
+
+	// Prerelease uses prereleases of the module
+	Prerelease bool `yaml:"prerelease,omitempty"`
+}
+
+// FindRoot returns the root directory of the repository dir is in: the
+// closest directory, starting at dir and going up, with a service.yaml
+func FindRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ServiceFile)); err == nil {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("failed to find %s", ServiceFile)
+		}
+		dir = parent
+	}
+}
+
+// Load finds the root of the repository dir is in, see FindRoot, and
+// loads its service.yaml
+func Load(dir string) (*Service, error) {
+	root, err := FindRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(filepath.Join(root, ServiceFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", ServiceFile)
+	}
+
+	s, err := Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	s.Dir = root
+	return s, nil
+}
+
+// Parse parses the contents of a service.yaml
+func Parse(b []byte) (*Service, error) {
+	var s Service
+	if err := yaml.Unmarshal(b, &s); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", ServiceFile)
+	}
+	return &s, nil
+}
@@ -0,0 +1,73 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const serviceYAML = `name: app
+arguments:
+  description: An app
+  ciOptions:
+    skipE2e: true
+    cacheVersion: 2
+  releaseOptions:
+    enablePrereleases: true
+    prereleasesBranch: main
+  vaultSecrets:
+    - deploy/app/%(environment)s/app
+  lintroller: platinum
+modules:
+  - name: github.com/getoutreach/stencil-golang
+    channel: unstable
+`
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "cmd", "app")
+	assert.NoError(t, os.MkdirAll(nested, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, ServiceFile), []byte(serviceYAML), 0o600))
+
+	s, err := Load(nested)
+	assert.NoError(t, err)
+	assert.Equal(t, &Service{
+		Dir:  root,
+		Name: "app",
+		Arguments: Arguments{
+			Description:    "An app",
+			CIOptions:      CIOptions{SkipE2e: true, Other: map[string]interface{}{"cacheVersion": 2}},
+			ReleaseOptions: ReleaseOptions{EnablePrereleases: true, PrereleasesBranch: "main"},
+			VaultSecrets:   []string{"deploy/app/%(environment)s/app"},
+			Other:          map[string]interface{}{"lintroller": "platinum"},
+		},
+		Modules: []Module{{Name: "github.com/getoutreach/stencil-golang", Channel: "unstable"}},
+	}, s)
+
+	_, err = Load(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestParseDocker(t *testing.T) {
+	d, err := ParseDocker([]byte(`app:
+  buildContext: .
+  pushTo: registry.example.com/app
+  secrets:
+    - id=mySecret,env=MY_SECRET
+  platforms:
+    - linux/amd64
+worker: {}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, Docker{
+		"app": {
+			BuildContext: ".",
+			PushTo:       "registry.example.com/app",
+			Secrets:      []string{"id=mySecret,env=MY_SECRET"},
+			Platforms:    []string{"linux/amd64"},
+		},
+		"worker": {},
+	}, d)
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the environment variable overrides of target configuration.
+
+package targets
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// lookupEnvFunc looks up an environment variable, like os.LookupEnv
+type lookupEnvFunc func(key string) (string, bool)
+
+// field is a setting of a configuration, a field that isn't a struct
+type field struct {
+	// Path are the yaml keys of the field, e.g. ["golang", "gcFlags"]
+	Path []string
+
+	// Env is the environment variable that overrides the field, if any
+	Env string
+
+	// Value is the field itself
+	Value reflect.Value
+}
+
+// fields returns all settings of the struct conf points to, including
+// those of nested structs
+func fields(conf interface{}) ([]field, error) {
+	v := reflect.ValueOf(conf)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a pointer to a struct, got %T", conf)
+	}
+	return structFields(v.Elem(), nil), nil
+}
+
+// structFields implements fields for a struct value
+func structFields(v reflect.Value, path []string) []field {
+	fs := make([]field, 0)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			// The default key of yaml.v3
+			name = strings.ToLower(f.Name)
+		}
+
+		// Copy the path, so that the paths of siblings don't share an array
+		p := append(append([]string{}, path...), name)
+		env := f.Tag.Get("env")
+		if env == "" && fv.Kind() == reflect.Struct {
+			fs = append(fs, structFields(fv, p)...)
+			continue
+		}
+		fs = append(fs, field{Path: p, Env: env, Value: fv})
+	}
+	return fs
+}
+
+// applyEnv sets every field of the struct conf points to, including
+// fields of nested structs, with an env tag to the value of that
+// environment variable when it's set. Strings, bools (true only when
+// exactly "true"), ints and string slices (comma separated) are supported.
+func applyEnv(conf interface{}, lookupEnv lookupEnvFunc) error {
+	fs, err := fields(conf)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fs {
+		if f.Env == "" {
+			continue
+		}
+		val, ok := lookupEnv(f.Env)
+		if !ok {
+			continue
+		}
+		if err := setField(f.Value, val); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", f.Env, val, err)
+		}
+	}
+	return nil
+}
+
+// setField sets a field from the string value of an environment variable
+func setField(fv reflect.Value, val string) error {
+	switch fv.Kind() { //nolint:exhaustive // Why: only these kinds are supported
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		// Only "true" is true, like the Makefile and the shell scripts
+		// compare it, e.g. SKIP_TRIMPATH=1 is false
+		fv.SetBool(val == "true")
+	case reflect.Int:
+		if val == "" {
+			fv.SetInt(0)
+			return nil
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("expected a number")
+		}
+		fv.SetInt(int64(n))
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", fv.Type())
+		}
+		items := make([]string, 0)
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
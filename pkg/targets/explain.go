@@ -0,0 +1,156 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the explanation of effective settings and their sources.
+
+package targets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Source is the layer the value of a setting came from
+type Source string
+
+// Contains the sources of settings
+const (
+	// SourceDefault is the built-in default of a setting
+	SourceDefault Source = "default"
+
+	// SourceEnv is an environment variable, e.g. set by the Makefile
+	SourceEnv Source = "env"
+
+	// SourceFile is a configuration file of the repository, e.g.
+	// .devbase/build.yaml or devenv.yaml
+	SourceFile Source = "file"
+
+	// SourceBox is the box configuration of the organization
+	SourceBox Source = "box"
+
+	// SourceGit is the git repository, e.g. the organization of its origin
+	SourceGit Source = "git"
+)
+
+// Setting is the effective value of a setting and where it came from
+type Setting struct {
+	// Name is the name of the setting, e.g. build.golang.gcFlags
+	Name string `json:"name"`
+
+	// Value is the effective value of the setting
+	Value string `json:"value"`
+
+	// Source is the layer the value came from
+	Source Source `json:"source"`
+
+	// Origin is where exactly in the source the value came from, e.g. the
+	// environment variable or the path of the file
+	Origin string `json:"origin,omitempty"`
+}
+
+// Settings are explained settings
+type Settings []Setting
+
+// Explain loads the configuration of a target, like LoadFrom, and returns
+// every setting of it, named <target>.<yaml keys>, with its source
+func Explain(root, target string, conf interface{}) (Settings, error) {
+	b, err := load(root, target, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", target)
+	}
+
+	fs, err := fields(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(Dir, target+".yaml")
+	settings := make(Settings, 0, len(fs))
+	for _, f := range fs {
+		s := Setting{
+			Name:   strings.Join(append([]string{target}, f.Path...), "."),
+			Value:  formatValue(f.Value),
+			Source: SourceDefault,
+		}
+		if _, ok := os.LookupEnv(f.Env); ok && f.Env != "" {
+			s.Source, s.Origin = SourceEnv, f.Env
+		} else if len(doc.Content) > 0 && lookup(doc.Content[0], f.Path...) != nil {
+			s.Source, s.Origin = SourceFile, path
+		}
+		settings = append(settings, s)
+	}
+	return settings, nil
+}
+
+// formatValue formats the value of a setting, slices are comma separated
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// lookup returns the value of the given path of keys in a mapping node,
+// or nil if there is none
+func lookup(n *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if n == nil || n.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+// Write writes the settings in the given format, either "text" or "json"
+func (s Settings) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE\tORIGIN")
+		for _, st := range s {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", st.Name, quoteEmpty(st.Value), st.Source, st.Origin)
+		}
+		return tw.Flush()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	default:
+		return fmt.Errorf("unknown format %q, expected one of [text json]", format)
+	}
+}
+
+// quoteEmpty returns v, or "" quoted when it's empty so that it's visible
+// in a table
+func quoteEmpty(v string) string {
+	if v == "" {
+		return `""`
+	}
+	return v
+}
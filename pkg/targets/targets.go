@@ -0,0 +1,101 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the loader of target configuration.
+
+// Package targets contains the shared library for targets that are
+// configured declaratively, as described in RFC-328. The configuration of
+// a target is read from .devbase/<target>.yaml in the root of the
+// repository into a Go struct, with defaults, overrides from environment
+// variables and validation.
+package targets
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/getoutreach/devbase/v2/pkg/manifest"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Dir is the directory, in the root of a repository, that the
+// configuration of targets is read from
+const Dir = ".devbase"
+
+// Defaulter is implemented by configurations that have defaults. Default
+// is called before the configuration file is read.
+type Defaulter interface {
+	Default()
+}
+
+// Validator is implemented by configurations that can be validated.
+// Validate is called after the configuration file and environment
+// variables have been applied.
+type Validator interface {
+	Validate() error
+}
+
+// Load reads the configuration of a target from .devbase/<target>.yaml in
+// the Root of the repository, see LoadFrom
+func Load(target string, conf interface{}) error {
+	return LoadFrom(Root(), target, conf)
+}
+
+// Root returns the root of the repository the current working directory
+// is in. When there is no service.yaml to find the root by, the current
+// working directory is used.
+func Root() string {
+	root, err := manifest.FindRoot(".")
+	if err != nil {
+		return "."
+	}
+	return root
+}
+
+// LoadFrom reads the configuration of a target from
+// <root>/.devbase/<target>.yaml into conf, a pointer to a struct:
+//
+//  1. Defaults are applied, if conf implements Defaulter.
+//  2. The configuration file, if it exists, is read. Unknown keys are an
+//     error.
+//  3. Environment variables override fields with an env tag, e.g.
+//     `env:"GC_FLAGS"`, when set.
+//  4. The configuration is validated, if conf implements Validator.
+func LoadFrom(root, target string, conf interface{}) error {
+	_, err := load(root, target, conf)
+	return err
+}
+
+// load implements LoadFrom, returning the contents of the configuration
+// file, nil when there is none
+func load(root, target string, conf interface{}) ([]byte, error) {
+	if d, ok := conf.(Defaulter); ok {
+		d.Default()
+	}
+
+	path := filepath.Join(root, Dir, target+".yaml")
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	if err == nil {
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(conf); err != nil && !errors.Is(err, io.EOF) {
+			return nil, errors.Wrapf(err, "failed to parse %s", path)
+		}
+	}
+
+	if err := applyEnv(conf, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if v, ok := conf.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s configuration", target)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,104 @@
+package targets
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConfig struct {
+	Name    string   `yaml:"name" env:"TEST_NAME"`
+	Nested  nested   `yaml:"nested"`
+	Untyped string   `yaml:"untyped"`
+	Tags    []string `yaml:"tags" env:"TEST_TAGS"`
+}
+
+type nested struct {
+	Enabled bool `yaml:"enabled" env:"TEST_ENABLED"`
+	Port    int  `yaml:"port" env:"TEST_PORT"`
+}
+
+func (c *testConfig) Default() {
+	c.Name = "default"
+	c.Nested.Port = 8080
+}
+
+func (c *testConfig) Validate() error {
+	if c.Name == "invalid" {
+		return errors.New("name is invalid")
+	}
+	return nil
+}
+
+func writeConfig(t *testing.T, contents string) string {
+	root := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(root, Dir), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, Dir, "test.yaml"), []byte(contents), 0o600))
+	return root
+}
+
+func TestLoadFromDefaults(t *testing.T) {
+	var c testConfig
+	assert.NoError(t, LoadFrom(t.TempDir(), "test", &c))
+	assert.Equal(t, testConfig{Name: "default", Nested: nested{Port: 8080}}, c)
+}
+
+func TestLoadFromFile(t *testing.T) {
+	root := writeConfig(t, "name: file\nnested:\n  enabled: true\n")
+
+	var c testConfig
+	assert.NoError(t, LoadFrom(root, "test", &c))
+	assert.Equal(t, testConfig{Name: "file", Nested: nested{Enabled: true, Port: 8080}}, c)
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	root := writeConfig(t, "name: file\nnested:\n  enabled: true\n")
+	t.Setenv("TEST_NAME", "env")
+	t.Setenv("TEST_ENABLED", "false")
+	t.Setenv("TEST_PORT", "42097")
+	t.Setenv("TEST_TAGS", "a, b,")
+
+	var c testConfig
+	assert.NoError(t, LoadFrom(root, "test", &c))
+	assert.Equal(t, testConfig{Name: "env", Nested: nested{Port: 42097}, Tags: []string{"a", "b"}}, c)
+}
+
+func TestLoadFromErrors(t *testing.T) {
+	var c testConfig
+	err := LoadFrom(writeConfig(t, "unknown: true\n"), "test", &c)
+	assert.ErrorContains(t, err, "field unknown not found")
+
+	t.Setenv("TEST_PORT", "port")
+	err = LoadFrom(t.TempDir(), "test", &c)
+	assert.EqualError(t, err, `invalid TEST_PORT "port": expected a number`)
+
+	t.Setenv("TEST_PORT", "")
+	err = LoadFrom(writeConfig(t, "name: invalid\n"), "test", &c)
+	assert.EqualError(t, err, "invalid test configuration: name is invalid")
+}
+
+func TestExplain(t *testing.T) {
+	root := writeConfig(t, "name: file\nnested:\n  enabled: true\n")
+	t.Setenv("TEST_PORT", "42097")
+
+	var c testConfig
+	settings, err := Explain(root, "test", &c)
+	assert.NoError(t, err)
+	assert.Equal(t, Settings{
+		{Name: "test.name", Value: "file", Source: SourceFile, Origin: filepath.Join(Dir, "test.yaml")},
+		{Name: "test.nested.enabled", Value: "true", Source: SourceFile, Origin: filepath.Join(Dir, "test.yaml")},
+		{Name: "test.nested.port", Value: "42097", Source: SourceEnv, Origin: "TEST_PORT"},
+		{Name: "test.untyped", Value: "", Source: SourceDefault},
+		{Name: "test.tags", Value: "", Source: SourceDefault},
+	}, settings)
+
+	var buf bytes.Buffer
+	assert.NoError(t, settings[2:4].Write(&buf, "text"))
+	assert.Equal(t, "SETTING           VALUE  SOURCE   ORIGIN\n"+
+		"test.nested.port  42097  env      TEST_PORT\n"+
+		"test.untyped      \"\"     default  \n", buf.String())
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the exporter that writes spans to a local JSON file.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// FileExporter appends spans to a file, one JSON object per line
+type FileExporter struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileExporter creates a FileExporter that appends spans to path,
+// creating it, and its directory, when needed
+func NewFileExporter(path string) *FileExporter {
+	return &FileExporter{path: path}
+}
+
+// Span is a span as it's written by a FileExporter
+type Span struct {
+	// Name is the name of the span
+	Name string `json:"name"`
+
+	// Service is the name of the service the span is of
+	Service string `json:"service,omitempty"`
+
+	// TraceID is the ID of the trace the span is part of
+	TraceID string `json:"traceId"`
+
+	// SpanID is the ID of the span
+	SpanID string `json:"spanId"`
+
+	// ParentSpanID is the ID of the parent of the span, if any
+	ParentSpanID string `json:"parentSpanId,omitempty"`
+
+	// Start is when the span started
+	Start time.Time `json:"start"`
+
+	// End is when the span ended
+	End time.Time `json:"end"`
+
+	// DurationMs is how long the span took, in milliseconds
+	DurationMs int64 `json:"durationMs"`
+
+	// Attributes are the attributes of the span
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+
+	// Status is the status of the span: Unset, Error or Ok
+	Status string `json:"status"`
+
+	// Error is the description of the status of a failed span
+	Error string `json:"error,omitempty"`
+}
+
+// ExportSpans implements sdktrace.SpanExporter
+func (e *FileExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(e.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(e.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, s := range spans {
+		if err := enc.Encode(newSpan(s)); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// Shutdown implements sdktrace.SpanExporter
+func (e *FileExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// newSpan returns the Span of a span
+func newSpan(s sdktrace.ReadOnlySpan) *Span {
+	span := &Span{
+		Name:       s.Name(),
+		TraceID:    s.SpanContext().TraceID().String(),
+		SpanID:     s.SpanContext().SpanID().String(),
+		Start:      s.StartTime(),
+		End:        s.EndTime(),
+		DurationMs: s.EndTime().Sub(s.StartTime()).Milliseconds(),
+		Status:     s.Status().Code.String(),
+		Error:      s.Status().Description,
+	}
+	if s.Parent().IsValid() {
+		span.ParentSpanID = s.Parent().SpanID().String()
+	}
+	if s.Resource() != nil {
+		if v, ok := s.Resource().Set().Value("service.name"); ok {
+			span.Service = v.AsString()
+		}
+	}
+	if attrs := s.Attributes(); len(attrs) > 0 {
+		span.Attributes = make(map[string]interface{}, len(attrs))
+		for _, kv := range attrs {
+			span.Attributes[string(kv.Key)] = kv.Value.AsInterface()
+		}
+	}
+	return span
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the setup of tracing for the mage targets and the e2e runner.
+
+// Package tracing exports spans of the mage targets and the e2e runner,
+// to OTLP when configured or otherwise to a local JSON file.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// DefaultFile is the file spans are written to when OTLP isn't configured
+const DefaultFile = "bin/traces.json"
+
+// instrumentationName is the name of the tracer of devbase
+const instrumentationName = "github.com/getoutreach/devbase/v2"
+
+// Contains the attributes set on spans
+const (
+	// AttrExitCode is the exit code of a command, or of the e2e runner
+	AttrExitCode = attribute.Key("process.exit.code")
+
+	// AttrCommand is a command, as it would be typed in a shell
+	AttrCommand = attribute.Key("process.command_line")
+
+	// AttrMageTarget is the name of a mage target
+	AttrMageTarget = attribute.Key("mage.target")
+
+	// AttrStage is the name of a stage of the e2e runner
+	AttrStage = attribute.Key("e2e.stage")
+
+	// AttrStageSkip is why a stage of the e2e runner was skipped
+	AttrStageSkip = attribute.Key("e2e.stage.skip")
+
+	// AttrE2EPackagesCount is the number of packages with e2e tests
+	AttrE2EPackagesCount = attribute.Key("e2e.packages.count")
+
+	// AttrProvisionTarget is the provision target (snapshot) of the devenv
+	AttrProvisionTarget = attribute.Key("devbase.provision.target")
+
+	// AttrGOOS is the operating system a build is for
+	AttrGOOS = attribute.Key("devbase.goos")
+
+	// AttrBuildPath is the path of the binaries of a build
+	AttrBuildPath = attribute.Key("build.path")
+
+	// AttrDepsCount is the number of dependencies of the application
+	AttrDepsCount = attribute.Key("devbase.deps.count")
+
+	// AttrAppName is the name of an application
+	AttrAppName = attribute.Key("app.name")
+
+	// AttrAppVersion is the version of an application
+	AttrAppVersion = attribute.Key("app.version")
+
+	// AttrChannel is the release channel an application is deployed to
+	AttrChannel = attribute.Key("devbase.channel")
+)
+
+// Start configures the global tracer provider to export the spans of the
+// given service. The exporter is configured by the environment:
+//
+//   - OTEL_TRACES_EXPORTER: "none" disables exporting, "otlp" exports to
+//     OTLP over gRPC (default: "otlp" when an OTLP endpoint is configured)
+//   - OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_TRACES_ENDPOINT and
+//     the other OTLP variables configure the OTLP exporter
+//   - DEVBASE_TRACES_FILE: the file spans are appended to, as JSON, when
+//     not exporting to OTLP (default: DefaultFile)
+//
+// The returned function exports pending spans and stops exporting, it
+// has to be called before exiting.
+func Start(ctx context.Context, service string) (func(context.Context) error, error) {
+	kind := os.Getenv("OTEL_TRACES_EXPORTER")
+	if kind == "" && (os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "") {
+		kind = "otlp"
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch kind {
+	case "none":
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exp, err := otlptracegrpc.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	default:
+		path := os.Getenv("DEVBASE_TRACES_FILE")
+		if path == "" {
+			path = DefaultFile
+		}
+		exporter = NewFileExporter(path)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the tracer of devbase
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End ends a span, marking it as failed when err isn't nil. The exit code
+// of a failed command is recorded.
+func End(span trace.Span, err error) {
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			span.SetAttributes(AttrExitCode.Int(exitErr.ExitCode()))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readSpans reads the spans written by a FileExporter
+func readSpans(t *testing.T, path string) []Span {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	var spans []Span
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var span Span
+		assert.NoError(t, json.Unmarshal(s.Bytes(), &span))
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+func TestStartFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bin", "traces.json")
+	t.Setenv("DEVBASE_TRACES_FILE", path)
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	// Spans are appended, so that the spans of several runs are kept
+	for i := 0; i < 2; i++ {
+		shutdown, err := Start(context.Background(), "devbase")
+		assert.NoError(t, err)
+
+		ctx, parent := Tracer().Start(context.Background(), "Gobuild")
+		parent.SetAttributes(AttrGOOS.String("linux"))
+		_, child := Tracer().Start(ctx, "go")
+		End(child, exec.Command("false").Run())
+		End(parent, nil)
+		assert.NoError(t, shutdown(context.Background()))
+	}
+
+	spans := readSpans(t, path)
+	assert.Len(t, spans, 4)
+
+	child, parent := spans[0], spans[1]
+	assert.Equal(t, "go", child.Name)
+	assert.Equal(t, "devbase", child.Service)
+	assert.Equal(t, parent.SpanID, child.ParentSpanID)
+	assert.Equal(t, parent.TraceID, child.TraceID)
+	assert.Equal(t, "Error", child.Status)
+	assert.Equal(t, "exit status 1", child.Error)
+	assert.Equal(t, float64(1), child.Attributes[string(AttrExitCode)])
+
+	assert.Equal(t, "Gobuild", parent.Name)
+	assert.Empty(t, parent.ParentSpanID)
+	assert.Equal(t, "Unset", parent.Status)
+	assert.Equal(t, map[string]interface{}{string(AttrGOOS): "linux"}, parent.Attributes)
+}
+
+func TestStartNone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	t.Setenv("DEVBASE_TRACES_FILE", path)
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+
+	shutdown, err := Start(context.Background(), "devbase")
+	assert.NoError(t, err)
+	_, span := Tracer().Start(context.Background(), "Dep")
+	End(span, errors.New("failed"))
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "no spans should be written")
+}
@@ -8,22 +8,27 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/getoutreach/devbase/v2/pkg/tracing"
 	"github.com/getoutreach/devbase/v2/root/e2e"
+	"github.com/getoutreach/devbase/v2/targets/build"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	logger "github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // log is the logger used by this magefile
 var log = logger.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
 // Dep installs all the dependencies needed to run the project.
-func Dep() error {
-	if err := runGoCommand(log, "mod", "download", "-x"); err != nil {
-		return err
-	}
+func Dep(ctx context.Context) error {
+	return traced(ctx, "Dep", func(_ context.Context, _ trace.Span) error {
+		if err := runGoCommand(log, "mod", "download", "-x"); err != nil {
+			return err
+		}
 
-	return runGoCommand(log, "mod", "tidy")
+		return runGoCommand(log, "mod", "tidy")
+	})
 }
 
 // Version prints the current application version
@@ -33,6 +38,11 @@ func Version() {
 
 // E2ETestBuild builds binaries of e2e tests
 func E2ETestBuild(ctx context.Context) error {
+	return traced(ctx, "E2ETestBuild", e2eTestBuild)
+}
+
+// e2eTestBuild implements E2ETestBuild
+func e2eTestBuild(_ context.Context, span trace.Span) error {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return err
@@ -43,12 +53,18 @@ func E2ETestBuild(ctx context.Context) error {
 		return err
 	}
 
+	conf, err := build.Load()
+	if err != nil {
+		return err
+	}
+
 	e2ePackages, err := e2e.GetE2eTestPaths(".", filepath.Walk, os.ReadDir, os.ReadFile)
 	if err != nil {
 		return errors.Wrap(err, "Error when searching e2e test packages")
 	}
+	span.SetAttributes(tracing.AttrE2EPackagesCount.Int(len(e2ePackages)), tracing.AttrGOOS.String(conf.Golang.GOOS))
 
-	if err := e2e.BuildE2ETestPackages(log, e2ePackages, binDir, runGoCommand); err != nil {
+	if err := e2e.BuildE2ETestPackages(log, e2ePackages, binDir, goCommand(conf)); err != nil {
 		return errors.Wrap(err, "Unable to build e2e test package")
 	}
 
@@ -67,6 +83,11 @@ func ensureBinDirExists(cwd string) (string, error) {
 
 // GoBuild builds a Go project
 func Gobuild(ctx context.Context) error {
+	return traced(ctx, "Gobuild", gobuild)
+}
+
+// gobuild implements Gobuild
+func gobuild(ctx context.Context, span trace.Span) error {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return err
@@ -85,6 +106,11 @@ func Gobuild(ctx context.Context) error {
 		return err
 	}
 
+	conf, err := build.Load()
+	if err != nil {
+		return err
+	}
+
 	honeycombKey, err := readSecret(ctx, "honeycomb/apiKey")
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to get honeycomb api key (did you run .bootstrap/shell/devconfig.sh?)")
@@ -100,7 +126,7 @@ func Gobuild(ctx context.Context) error {
 		"main.HoneycombTracingKey":                     string(honeycombKey),
 		"main.TeleforkAPIKey":                          string(teleforkKey),
 	})
-	if os.Getenv("DLV_PORT") == "" {
+	if conf.Golang.StripSymbols() {
 		// When not running in DLV, strip out symbols
 		ldFlags += "-w -s"
 	}
@@ -113,14 +139,15 @@ func Gobuild(ctx context.Context) error {
 	if pluginDirErr == nil {
 		buildPath = "./plugin"
 	}
+	span.SetAttributes(tracing.AttrBuildPath.String(buildPath), tracing.AttrGOOS.String(conf.Golang.GOOS))
 
 	args := []string{"build", "-v", "-o", binDir, "-ldflags", ldFlags}
-	if gcFlags := os.Getenv("GC_FLAGS"); gcFlags != "" {
+	if gcFlags := conf.Golang.GCFlags; gcFlags != "" {
 		args = append(args, "-gcflags", gcFlags)
 	}
 
-	// SKIP_TRIMPATH is used for devspace binary sync, where you want to have same file paths for delve to work correctly
-	if os.Getenv("SKIP_TRIMPATH") == "true" {
+	// Skipping trimpath is used for devspace binary sync, where you want to have same file paths for delve to work correctly
+	if conf.Golang.SkipTrimpath {
 		log.Debug().Msg("Skipping trimpath argument for go build")
 	} else {
 		// Build with -trimpath to ensure we have consistent module filenames embedded.
@@ -129,5 +156,5 @@ func Gobuild(ctx context.Context) error {
 
 	args = append(args, buildPath+"/...")
 
-	return runGoCommand(log, args...)
+	return runGoCommandWith(log, conf, args...)
 }
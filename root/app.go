@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/getoutreach/devbase/v2/pkg/manifest"
 	"github.com/getoutreach/gobox/pkg/cfg"
 	"github.com/magefile/mage/sh"
 )
@@ -23,8 +24,13 @@ func getAppVersion() string {
 	return version
 }
 
-// getAppName returns the app name
+// getAppName returns the app name from service.yaml, falling back to the
+// name of the current directory when there is none
 func getAppName() string {
+	if s, err := manifest.Load("."); err == nil && s.Name != "" {
+		return s.Name
+	}
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		return "unknown"
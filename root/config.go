@@ -0,0 +1,220 @@
+//go:build mage
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/e2e/deps"
+	"github.com/getoutreach/devbase/v2/pkg/targets"
+	"github.com/getoutreach/devbase/v2/targets/build"
+	"github.com/getoutreach/gobox/pkg/box"
+	"github.com/magefile/mage/mg"
+	"github.com/magefile/mage/sh"
+	"github.com/pkg/errors"
+)
+
+// Config contains targets for inspecting the configuration of the project
+type Config mg.Namespace
+
+// Explain prints every effective setting of the go commands, gobuild and the e2e runner along with where its value
+// came from (default, env, file, box or git), format is one of "text" or "json"
+func (Config) Explain(ctx context.Context, format string) error {
+	settings, err := explainGo()
+	if err != nil {
+		return err
+	}
+
+	bs, err := build.Explain()
+	if err != nil {
+		return err
+	}
+	settings = append(settings, bs...)
+
+	es, err := explainE2E(ctx)
+	if err != nil {
+		return err
+	}
+	settings = append(settings, es...)
+
+	return settings.Write(os.Stdout, format)
+}
+
+// envSetting returns a setting with the effective value of a setting
+// read from the environment variable key, whose source is that variable
+// when it's set, or the default otherwise. The value is parsed like the
+// consumer of the setting does, e.g. SKIP_LOCALIZER=1 is false.
+func envSetting(name, key string, value interface{}) targets.Setting {
+	if os.Getenv(key) != "" {
+		return targets.Setting{Name: name, Value: fmt.Sprint(value), Source: targets.SourceEnv, Origin: key}
+	}
+	return targets.Setting{Name: name, Value: fmt.Sprint(value), Source: targets.SourceDefault}
+}
+
+// explainGo explains the environment variables set by runGoCommand, the
+// GOOS is part of the build configuration
+func explainGo() (targets.Settings, error) {
+	goFlags := targets.Setting{Name: "go.GOFLAGS", Value: "-tags=or_dev", Source: targets.SourceDefault}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		goFlags = targets.Setting{Name: "go.GOFLAGS", Source: targets.SourceEnv, Origin: "KUBERNETES_SERVICE_HOST"}
+	}
+
+	org, host, source, err := getOrgWithSource()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to determine org")
+	}
+	origin := "org"
+	if source == targets.SourceGit {
+		origin = "remote origin"
+	}
+	goPrivate := targets.Setting{Name: "go.GOPRIVATE", Value: goPrivate(host, org), Source: source, Origin: origin}
+
+	// CGO_ENABLED isn't set by runGoCommand, it's inherited from the
+	// environment or defaulted by go itself
+	cgo := envSetting("go.CGO_ENABLED", "CGO_ENABLED", os.Getenv("CGO_ENABLED"))
+	if cgo.Source == targets.SourceDefault {
+		if cgo.Value, err = sh.Output("go", "env", "CGO_ENABLED"); err != nil {
+			return nil, errors.Wrap(err, "failed to get default of CGO_ENABLED")
+		}
+		cgo.Origin = "go env"
+	}
+
+	return targets.Settings{goFlags, goPrivate, cgo}, nil
+}
+
+// explainE2E explains the settings of the e2e runner, parsed like the
+// runner parses them
+func explainE2E(ctx context.Context) (targets.Settings, error) {
+	conf, err := box.EnsureBoxWithOptions(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load box config")
+	}
+
+	env, err := config.RunnerFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := deps.OptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	dc, err := config.FromFile("devenv.yaml")
+	if err != nil {
+		dc = &config.Devenv{}
+	}
+
+	p, forgeOrg, err := opts.NewForge(conf)
+	if err != nil {
+		return nil, err
+	}
+	org := envSetting("e2e.forgeOrg", "E2E_FORGE_ORG", forgeOrg)
+	if org.Source == targets.SourceDefault {
+		org.Source, org.Origin = targets.SourceBox, "org"
+		if forgeOrg != conf.Org {
+			org.Source, org.Origin = targets.SourceGit, "remote origin"
+		}
+	}
+
+	localRoot := opts.LocalSourceRoot
+	if localRoot == "" {
+		if localRoot, err = deps.DefaultLocalSourceRoot(); err != nil {
+			return nil, err
+		}
+	}
+
+	optional := envSetting("e2e.optionalDependencies", "E2E_OPTIONAL_DEPS", deps.OptionalPolicy(opts.Optional, dc))
+	if optional.Source == targets.SourceDefault && dc.Resolution.OptionalDependencies != "" {
+		optional.Source, optional.Origin = targets.SourceFile, "devenv.yaml"
+	}
+
+	maxDepth := envSetting("e2e.maxDepth", "E2E_MAX_DEPTH", deps.MaxDepth(opts.MaxDepth, dc))
+	if opts.MaxDepth == 0 && dc.Resolution.MaxDepth != 0 {
+		maxDepth.Source, maxDepth.Origin = targets.SourceFile, "devenv.yaml"
+	}
+
+	return targets.Settings{
+		explainProvisionTarget(ctx, conf, opts, dc),
+		envSetting("e2e.skipProvision", "SKIP_DEVENV_PROVISION", env.SkipProvision),
+		envSetting("e2e.skipLocalizer", "SKIP_LOCALIZER", env.SkipLocalizer),
+		envSetting("e2e.devconfigAfterDeploy", "REQUIRE_DEVCONFIG_AFTER_DEPLOY", env.DevconfigAfterDeploy),
+		envSetting("e2e.useDevspace", "USE_DEVSPACE", env.UseDevspace),
+		// The default teardown policy depends on the run, see the docs of e2e
+		envSetting("e2e.teardown", "E2E_TEARDOWN", env.Teardown),
+		envSetting("e2e.deployWaves", "E2E_DEPLOY_WAVES", env.DeployWaves),
+		envSetting("e2e.readyTimeout", "E2E_READY_TIMEOUT", env.ReadyTimeout),
+		envSetting("e2e.profile", "E2E_PROFILE", opts.Profile),
+		envSetting("e2e.dependencySources", "E2E_DEPENDENCY_SOURCES", strings.Join(opts.Sources, ",")),
+		envSetting("e2e.forge", "E2E_FORGE", p.Name()),
+		envSetting("e2e.forgeURL", "E2E_FORGE_URL", opts.ForgeURL),
+		org,
+		envSetting("e2e.localSourceRoot", "E2E_LOCAL_SOURCE_ROOT", localRoot),
+		envSetting("e2e.resolveConcurrency", "E2E_RESOLVE_CONCURRENCY", opts.Concurrency),
+		envSetting("e2e.dependencyCycles", "E2E_DEPENDENCY_CYCLES", opts.Cycles),
+		optional,
+		maxDepth,
+		envSetting("e2e.depsCache", "E2E_DEPS_CACHE", opts.Cache),
+		envSetting("e2e.depsCacheTTL", "E2E_DEPS_CACHE_TTL", opts.CacheTTL),
+		envSetting("e2e.depsCacheRefresh", "E2E_DEPS_CACHE_REFRESH", opts.Refresh),
+	}, nil
+}
+
+// explainProvisionTarget explains the provision target the e2e runner
+// provisions the devenv with, which requires resolving the dependencies,
+// of the profile when one is used
+func explainProvisionTarget(ctx context.Context, conf *box.Config, opts *deps.Options,
+	dc *config.Devenv) targets.Setting {
+	s := envSetting("e2e.provisionTarget", "PROVISION_TARGET", os.Getenv("PROVISION_TARGET"))
+	if s.Source == targets.SourceEnv {
+		return s
+	}
+
+	g, err := deps.ResolveCurrent(ctx, conf, opts)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to resolve dependencies, can't explain the provision target")
+		s.Value, s.Origin = "unknown", "dependencies could not be resolved"
+		return s
+	}
+
+	bd, err := config.LoadBoxDevbase()
+	if err != nil {
+		bd = &config.BoxDevbase{}
+	}
+
+	var rule *config.ProvisionTargetRule
+	s.Value, rule = g.ProvisionTarget(dc.ProvisionTargets, bd.ProvisionTargets)
+	switch {
+	case rule != nil && hasRule(dc.ProvisionTargets.Rules, *rule):
+		s.Source, s.Origin = targets.SourceFile, "devenv.yaml rule for "+rule.Dependency
+	case rule != nil && hasRule(bd.ProvisionTargets.Rules, *rule):
+		s.Source, s.Origin = targets.SourceBox, "devbase.provisionTargets rule for "+rule.Dependency
+	case rule != nil && hasRule(deps.DefaultProvisionTargetRules, *rule):
+		s.Origin = "rule for " + rule.Dependency
+	case rule != nil:
+		// The provision target the dependency itself declared it needs
+		s.Source, s.Origin = targets.SourceFile, "devenv.yaml of "+rule.Dependency
+	case dc.ProvisionTargets.Default != "":
+		s.Source, s.Origin = targets.SourceFile, "devenv.yaml"
+	case bd.ProvisionTargets.Default != "":
+		s.Source, s.Origin = targets.SourceBox, "devbase.provisionTargets"
+	}
+	if rule != nil && opts.Profile != "" {
+		s.Origin += " in profile " + opts.Profile
+	}
+	return s
+}
+
+// hasRule returns whether rules contains the given rule
+func hasRule(rules []config.ProvisionTargetRule, rule config.ProvisionTargetRule) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -11,17 +11,28 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/getoutreach/devbase/v2/pkg/tracing"
 	"github.com/getoutreach/gobox/pkg/box"
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	logger "github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Deploy pushs new actionable version to maestro for given app and channel
 func Deploy(ctx context.Context, appName, channel string) error {
+	return traced(ctx, "Deploy", func(ctx context.Context, span trace.Span) error {
+		return deploy(ctx, span, appName, channel)
+	})
+}
+
+// deploy implements Deploy
+func deploy(_ context.Context, span trace.Span, appName, channel string) error {
 	log := logger.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	appVersion := getAppVersion()
+	span.SetAttributes(tracing.AttrAppName.String(appName), tracing.AttrAppVersion.String(appVersion),
+		tracing.AttrChannel.String(channel))
 
 	log.Info().Msgf("Triggering deployment for %s version=%q channel=%q", appName, appVersion, channel)
 
@@ -0,0 +1,81 @@
+//go:build mage
+
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/getoutreach/devbase/v2/e2e/deps"
+	"github.com/getoutreach/gobox/pkg/box"
+	"github.com/magefile/mage/mg"
+	"github.com/pkg/errors"
+)
+
+// Deps contains targets for inspecting the devenv dependencies of the project
+type Deps mg.Namespace
+
+// Graph prints the resolved dependency tree of the project, format is one of "dot" or "json"
+func (Deps) Graph(ctx context.Context, format string) error {
+	g, err := resolveDependencies(ctx)
+	if err != nil {
+		return err
+	}
+
+	return g.Write(os.Stdout, format)
+}
+
+// Plan prints the order the dependencies of the project are deployed in, format is one of "text" or "json"
+func (Deps) Plan(ctx context.Context, format string) error {
+	g, err := resolveDependencies(ctx)
+	if err != nil {
+		return err
+	}
+
+	return g.Plan().Write(os.Stdout, format)
+}
+
+// resolveDependencies resolves the dependency graph of the project
+func resolveDependencies(ctx context.Context) (*deps.Graph, error) {
+	conf, err := box.EnsureBoxWithOptions(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load box config")
+	}
+
+	opts, err := deps.OptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := deps.ResolveCurrent(ctx, conf, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve dependencies")
+	}
+	return g, nil
+}
+
+// Diff prints the dependencies of the project that were added, removed or pinned to a different ref going from
+// the git revision base to head (e.g. "origin/main" and "HEAD"), with the path of dependencies that pulls each of
+// them in. Format is one of "text" or "json".
+func (Deps) Diff(ctx context.Context, base, head, format string) error {
+	conf, err := box.EnsureBoxWithOptions(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to load box config")
+	}
+
+	opts, err := deps.OptionsFromEnv()
+	if err != nil {
+		return err
+	}
+
+	graphs := make([]*deps.Graph, 2)
+	for i, rev := range []string{base, head} {
+		g, err := deps.ResolveRevision(ctx, conf, opts, rev)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve dependencies at %s", rev)
+		}
+		graphs[i] = g
+	}
+
+	return deps.DiffGraphs(graphs[0], graphs[1]).Write(os.Stdout, format)
+}
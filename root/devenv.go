@@ -0,0 +1,53 @@
+//go:build mage
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/magefile/mage/mg"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Devenv contains targets for managing the devenv.yaml of the project
+type Devenv mg.Namespace
+
+// Migrate rewrites the devenv.yaml of the project from the legacy format to the v2 schema (apiVersion: v2).
+// Comments are not preserved.
+func (Devenv) Migrate() error {
+	const file = "devenv.yaml"
+	info, err := os.Stat(file)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", file)
+	}
+
+	dc, err := config.FromFile(file)
+	if err != nil {
+		return err
+	}
+	if dc.APIVersion == config.APIVersionV2 {
+		fmt.Printf("%s already uses apiVersion %s\n", file, config.APIVersionV2)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(dc.V2()); err != nil {
+		return errors.Wrapf(err, "failed to encode %s", file)
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(file, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return errors.Wrapf(err, "failed to write %s", file)
+	}
+
+	fmt.Printf("Migrated %s to apiVersion %s\n", file, config.APIVersionV2)
+	return nil
+}
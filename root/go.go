@@ -4,11 +4,12 @@ package main
 
 import (
 	"fmt"
-	"net/url"
 	"os"
-	"strings"
 
-	giturls "github.com/chainguard-dev/git-urls"
+	"github.com/getoutreach/devbase/v2/pkg/forge"
+	"github.com/getoutreach/devbase/v2/pkg/targets"
+	"github.com/getoutreach/devbase/v2/root/e2e"
+	"github.com/getoutreach/devbase/v2/targets/build"
 	"github.com/getoutreach/gobox/pkg/box"
 	"github.com/magefile/mage/sh"
 	"github.com/pkg/errors"
@@ -16,69 +17,84 @@ import (
 	"github.com/rs/zerolog"
 )
 
-func pathFromGitURL(origin string) (string, error) {
-	if strings.HasPrefix(origin, "https://") {
-		u, err := url.Parse(origin)
-		if err != nil {
-			return "", errors.Wrap(err, "failed to parse HTTPS URL")
-		}
-		return strings.TrimPrefix(u.Path, "/"), nil
-	}
-
-	u, err := giturls.Parse(origin)
+// getOrgWithSource returns the organization of the current repository,
+// e.g. "getoutreach", or for GitLab subgroups "group/subgroup", along with
+// the host of its forge and where the organization was read from. The
+// host is read from the git origin, defaulting to github.com when it has
+// none, e.g. for file remotes.
+func getOrgWithSource() (org, host string, source targets.Source, err error) {
+	origin, err := sh.Output("git", "remote", "get-url", "origin")
 	if err != nil {
-		return "", errors.Wrap(err, "failed to parse SSH URL")
+		err = errors.Wrap(err, "failed to get git origin")
 	}
-	return u.Path, nil
-}
-
-// getOrg returns the Github organization name of the current repository
-func getOrg() (string, error) {
-	conf, err := box.LoadBox()
+	var r *forge.Remote
 	if err == nil {
-		return conf.Org, nil
+		r, err = forge.ParseRemote(origin)
+	}
+	host = "github.com"
+	if r != nil && r.Host != "" {
+		host = r.Host
 	}
 
-	// Fallback to reading the git origin
-	origin, err := sh.Output("git", "remote", "get-url", "origin")
-	if err != nil {
-		return "", errors.Wrap(err, "failed to get git origin")
+	if conf, berr := box.LoadBox(); berr == nil {
+		return conf.Org, host, targets.SourceBox, nil
 	}
 
-	urlPath, err := pathFromGitURL(origin)
+	// Fallback to the organization of the git origin
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to parse git origin %q", origin)
+		return "", "", "", err
 	}
 
-	// ["getoutreach", "gobox"]
-	spl := strings.Split(urlPath, "/")
-	if len(spl) != 2 {
-		return "", fmt.Errorf("failed to parse org from git origin %q", origin)
+	org = r.Org()
+	if org == "" {
+		return "", "", "", fmt.Errorf("failed to parse org from git origin %q", origin)
 	}
+	return org, host, targets.SourceGit, nil
+}
 
-	return spl[0], nil
+// goPrivate returns the GOPRIVATE pattern of the modules of an
+// organization on the forge at host, e.g. "gitlab.com/group/subgroup/*"
+func goPrivate(host, org string) string {
+	return fmt.Sprintf("%s/%s/*", host, org)
 }
 
 // runGoCommand runs the given go command with the given arguments
-// while setting required environment variables
+// while setting required environment variables. Only the build targets
+// read the build configuration of the current repository, the GOOS is
+// only read from BUILD_FOR_GOOS here.
 func runGoCommand(log zerolog.Logger, args ...string) error {
+	conf := &build.Config{Golang: build.Golang{GOOS: os.Getenv("BUILD_FOR_GOOS")}}
+	return runGoCommandWith(log, conf, args...)
+}
+
+// goCommand returns a function that runs the given go command with the
+// given arguments while setting required environment variables from the
+// given build configuration
+func goCommand(conf *build.Config) e2e.RunGoCommand {
+	return func(log zerolog.Logger, args ...string) error {
+		return runGoCommandWith(log, conf, args...)
+	}
+}
+
+// runGoCommandWith implements goCommand
+func runGoCommandWith(log zerolog.Logger, conf *build.Config, args ...string) error {
 	goFlags := ""
 	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
 		// When not running in Kubernetes, build in or_dev mode
 		goFlags = "-tags=or_dev"
 	}
 
-	org, err := getOrg()
+	org, host, _, err := getOrgWithSource()
 	if err != nil {
-		return errors.Wrap(err, "failed to get determine org")
+		return errors.Wrap(err, "failed to determine org")
 	}
 
 	vars := map[string]string{
 		"GOFLAGS": goFlags,
 		// TODO(jaredallard): We may not always want to set GOPRIVATE...
-		"GOPRIVATE": fmt.Sprintf("github.com/%s/*", org)}
+		"GOPRIVATE": goPrivate(host, org)}
 
-	if goos := os.Getenv("BUILD_FOR_GOOS"); goos != "" { // BUILD_FOR_GOOS is used when we build on macos for linux
+	if goos := conf.Golang.GOOS; goos != "" { // Used when we build on macos for linux
 		log.Info().Msgf("Building for GOOS %s", goos)
 		vars["GOOS"] = goos
 	}
@@ -0,0 +1,33 @@
+//go:build mage
+
+package main
+
+import (
+	"context"
+
+	"github.com/getoutreach/devbase/v2/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traced runs a mage target in a span named after it, see tracing.Start
+// for where the span is exported to. Failing to export only logs a
+// warning.
+func traced(ctx context.Context, target string, fn func(ctx context.Context, span trace.Span) error) (err error) {
+	shutdown, serr := tracing.Start(ctx, "devbase")
+	if serr != nil {
+		log.Warn().Err(serr).Msg("Failed to start tracing, spans are not exported")
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, target, trace.WithAttributes(tracing.AttrMageTarget.String(target)))
+	defer func() {
+		tracing.End(span, err)
+		if shutdown == nil {
+			return
+		}
+		if err := shutdown(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("Failed to export spans")
+		}
+	}()
+
+	return fn(ctx, span)
+}
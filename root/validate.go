@@ -0,0 +1,29 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/pkg/errors"
+)
+
+// Validate strictly validates the devenv.yaml and service.yaml of the project, reporting unknown keys and
+// invalid dependencies with their position. Problems with the keys of service.yaml that belong to stencil are
+// only warnings.
+func Validate() error {
+	if err := config.ValidateFiles("devenv.yaml", "service.yaml"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		var verrs config.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return errors.New("configuration is invalid")
+		}
+		if _, severe := verrs.Split(); len(severe) > 0 {
+			return errors.New("configuration is invalid")
+		}
+	}
+	return nil
+}
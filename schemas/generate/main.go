@@ -0,0 +1,27 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the command that writes the JSON Schemas of the configuration files.
+
+// Package main writes the JSON Schemas of the configuration files into
+// the current working directory, see go generate in the schemas package.
+package main
+
+import (
+	"os"
+
+	"github.com/getoutreach/devbase/v2/schemas"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	files, err := schemas.Generate()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to generate schemas")
+	}
+
+	for name, b := range files {
+		if err := os.WriteFile(name, b, 0o644); err != nil { //nolint:gosec // Why: schemas are public
+			log.Fatal().Err(err).Str("file", name).Msg("Failed to write schema")
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the generation of the JSON Schemas of the configuration files.
+
+//go:generate go run ./generate
+
+// Package schemas contains the JSON Schemas of the configuration files of
+// a repository using devbase, generated from the Go types they're decoded
+// into. Editors using yaml-language-server can validate, and autocomplete,
+// a file by referring to its schema at the top of the file:
+//
+//	# yaml-language-server: $schema=https://raw.githubusercontent.com/getoutreach/devbase/main/schemas/devenv.schema.json
+package schemas
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"runtime"
+
+	"github.com/getoutreach/devbase/v2/e2e/config"
+	"github.com/getoutreach/devbase/v2/pkg/jsonschema"
+	"github.com/getoutreach/devbase/v2/pkg/manifest"
+	"github.com/pkg/errors"
+)
+
+// baseURL is the URL the schemas are published at
+const baseURL = "https://raw.githubusercontent.com/getoutreach/devbase/main/schemas/"
+
+// dependencyName matches valid names of dependencies, which are
+// repository names other than "." and "..", see config.ValidateName
+const dependencyName = `([A-Za-z0-9_-][A-Za-z0-9._-]{0,99}|\.[A-Za-z0-9_-][A-Za-z0-9._-]{0,98}|\.\.[A-Za-z0-9._-]{1,98})`
+
+// Files are the committed schemas, keyed by file name
+//
+//go:embed *.schema.json
+var Files embed.FS
+
+// packages are the packages, relative to the root of the repository, whose
+// doc comments are used as descriptions, keyed by import path
+var packages = map[string]string{
+	"github.com/getoutreach/devbase/v2/e2e/config":   "e2e/config",
+	"github.com/getoutreach/devbase/v2/pkg/manifest": "pkg/manifest",
+}
+
+// Generate generates the schemas, keyed by file name
+func Generate() (map[string][]byte, error) {
+	g, err := newGenerator()
+	if err != nil {
+		return nil, err
+	}
+
+	// devenv.yaml is the legacy format, unless its apiVersion is v2
+	v1, v2 := g.Reflect(config.Devenv{}), g.Reflect(config.DevenvV2{})
+	defs := g.Definitions()
+	defs["Devenv"].Properties["apiVersion"].Enum = []interface{}{config.APIVersionV1}
+	defs["DevenvV2"].Properties["apiVersion"].Const = config.APIVersionV2
+	devenv := &jsonschema.Schema{
+		Title: "devenv.yaml",
+		If: &jsonschema.Schema{
+			Properties: map[string]*jsonschema.Schema{"apiVersion": {Const: config.APIVersionV2}},
+			Required:   []string{"apiVersion"},
+		},
+		Then: v2,
+		Else: v1,
+	}
+
+	service := g.Reflect(config.ServiceManifest{})
+	service.Title = "service.yaml"
+
+	docker := g.Reflect(manifest.Docker{})
+	docker.Title = "deployments/docker.yaml"
+
+	files := make(map[string][]byte)
+	for name, s := range map[string]*jsonschema.Schema{
+		"devenv.schema.json":  devenv,
+		"service.schema.json": service,
+		"docker.schema.json":  docker,
+	} {
+		s.Schema, s.ID = jsonschema.Draft07, baseURL+name
+		s.Definitions = referenced(s, defs)
+
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(s); err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal %s", name)
+		}
+		files[name] = buf.Bytes()
+	}
+	return files, nil
+}
+
+// newGenerator creates the generator of the schemas, with the types whose
+// YAML form differs from their Go type overridden
+func newGenerator() (*jsonschema.Generator, error) {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return nil, errors.New("failed to find the source of the schemas package")
+	}
+	root := filepath.Join(filepath.Dir(file), "..")
+
+	comments := make(jsonschema.Comments)
+	for pkgPath, dir := range packages {
+		if err := jsonschema.LoadComments(comments, filepath.Join(root, dir), pkgPath); err != nil {
+			return nil, err
+		}
+	}
+
+	g := jsonschema.NewGenerator(comments)
+
+	// A dependency is either "name", "name@ref" or a mapping
+	g.Overrides[reflect.TypeOf(config.Dependency{})] = func(s *jsonschema.Schema) *jsonschema.Schema {
+		s.Properties["name"].Pattern = "^" + dependencyName + "$"
+		s.Required = []string{"name"}
+		description := s.Description
+		s.Description = ""
+		return &jsonschema.Schema{
+			Description: description,
+			OneOf: []*jsonschema.Schema{
+				{Type: "string", Pattern: "^" + dependencyName + "(@.+)?$", Description: "The name of the service, or name@ref"},
+				s,
+			},
+		}
+	}
+	g.Overrides[reflect.TypeOf(config.OptionalPolicy(""))] = func(s *jsonschema.Schema) *jsonschema.Schema {
+		s.Enum = []interface{}{config.OptionalNone, config.OptionalRoot, config.OptionalAll}
+		return s
+	}
+	return g, nil
+}
+
+// referenced returns the definitions that a schema refers to, directly or
+// through other definitions
+func referenced(s *jsonschema.Schema, defs map[string]*jsonschema.Schema) map[string]*jsonschema.Schema {
+	refs := make(map[string]*jsonschema.Schema)
+	var walk func(s *jsonschema.Schema)
+	walk = func(s *jsonschema.Schema) {
+		if s == nil {
+			return
+		}
+		if name := filepath.Base(s.Ref); s.Ref != "" && refs[name] == nil {
+			refs[name] = defs[name]
+			walk(defs[name])
+		}
+		for _, p := range s.Properties {
+			walk(p)
+		}
+		if ap, ok := s.AdditionalProperties.(*jsonschema.Schema); ok {
+			walk(ap)
+		}
+		for _, o := range s.OneOf {
+			walk(o)
+		}
+		walk(s.Items)
+		walk(s.If)
+		walk(s.Then)
+		walk(s.Else)
+	}
+	walk(s)
+	return refs
+}
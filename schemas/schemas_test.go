@@ -0,0 +1,20 @@
+package schemas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemasUpToDate(t *testing.T) {
+	files, err := Generate()
+	assert.NoError(t, err)
+
+	for name, b := range files {
+		committed, err := Files.ReadFile(name)
+		if !assert.NoError(t, err, "%s is missing, run go generate ./schemas", name) {
+			continue
+		}
+		assert.Equal(t, string(b), string(committed), "%s is out of date, run go generate ./schemas", name)
+	}
+}
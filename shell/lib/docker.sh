@@ -15,7 +15,8 @@ source "${DIR}/yaml.sh"
 
 # get_image_field is a helper to return a field from the manifest
 # for a given image. It will return an empty string if the field
-# is not set.
+# is not set. The fields are documented by manifest.Image in
+# pkg/manifest/docker.go, and schemas/docker.schema.json.
 #
 # Arguments:
 #   $1 - image name
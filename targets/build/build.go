@@ -0,0 +1,87 @@
+// Copyright 2024 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the configuration of the build targets.
+
+// Package build contains the configuration of the build targets, gobuild
+// and e2etestbuild, read from .devbase/build.yaml.
+package build
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/getoutreach/devbase/v2/pkg/targets"
+)
+
+// Target is the name of the target, its configuration is read from
+// .devbase/build.yaml
+const Target = "build"
+
+// goosRe matches valid values of GOOS
+var goosRe = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// Config is the configuration of the build targets.
+//
+// Example .devbase/build.yaml:
+//
+//	golang:
+//	  gcFlags: all=-N -l
+//	  skipTrimpath: true
+//	  goos: linux
+type Config struct {
+	// Golang is the configuration of building Go code
+	Golang Golang `yaml:"golang"`
+}
+
+// Golang is the configuration of building Go code
+type Golang struct {
+	// GCFlags are passed to go build as -gcflags, e.g. "all=-N -l" to
+	// disable optimizations for debugging. Overridden by GC_FLAGS.
+	GCFlags string `yaml:"gcFlags" env:"GC_FLAGS"`
+
+	// SkipTrimpath builds without -trimpath, so the file paths of the
+	// binaries match the source for delve, e.g. when syncing binaries to
+	// devspace. Overridden by SKIP_TRIMPATH.
+	SkipTrimpath bool `yaml:"skipTrimpath" env:"SKIP_TRIMPATH"`
+
+	// GOOS is the operating system to build for, e.g. "linux" when
+	// building on macOS for devspace. Defaults to the current one.
+	// Overridden by BUILD_FOR_GOOS.
+	GOOS string `yaml:"goos" env:"BUILD_FOR_GOOS"`
+
+	// DebugPort is the port delve listens on. When set, to anything,
+	// symbols are not stripped from the binaries. Overridden by DLV_PORT,
+	// which is passed to delve as-is.
+	DebugPort string `yaml:"debugPort" env:"DLV_PORT"`
+}
+
+// StripSymbols returns whether symbols should be stripped from binaries,
+// which is the case unless they're going to be debugged
+func (g *Golang) StripSymbols() bool {
+	return g.DebugPort == ""
+}
+
+// Validate implements targets.Validator
+func (c *Config) Validate() error {
+	if c.Golang.GOOS != "" && !goosRe.MatchString(c.Golang.GOOS) {
+		return fmt.Errorf("golang.goos %q is not a valid GOOS, e.g. linux", c.Golang.GOOS)
+	}
+	return nil
+}
+
+// Load loads the configuration of the build targets of the current
+// repository
+func Load() (*Config, error) {
+	var c Config
+	if err := targets.Load(Target, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Explain loads the configuration of the build targets of the current
+// repository, returning every setting with its source
+func Explain() (targets.Settings, error) {
+	var c Config
+	return targets.Explain(targets.Root(), Target, &c)
+}
@@ -0,0 +1,56 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getoutreach/devbase/v2/pkg/targets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigEnvCompatibility(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(root, targets.Dir), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, targets.Dir, "build.yaml"),
+		[]byte("golang:\n  gcFlags: all=-N\n  goos: darwin\n"), 0o600))
+
+	// The variables set by `make devspace`
+	t.Setenv("BUILD_FOR_GOOS", "linux")
+	t.Setenv("SKIP_TRIMPATH", "true")
+	t.Setenv("DLV_PORT", "42097")
+	t.Setenv("GC_FLAGS", "all=-N -l")
+
+	var c Config
+	assert.NoError(t, targets.LoadFrom(root, Target, &c))
+	assert.Equal(t, Golang{GCFlags: "all=-N -l", SkipTrimpath: true, GOOS: "linux", DebugPort: "42097"}, c.Golang)
+	assert.False(t, c.Golang.StripSymbols())
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+	assert.True(t, (&Config{}).Golang.StripSymbols())
+	assert.Error(t, (&Config{Golang: Golang{GOOS: "Linux OS"}}).Validate())
+}
+
+func TestConfigEnvSemantics(t *testing.T) {
+	// Like the Makefile, only exactly "true" skips -trimpath and any
+	// DLV_PORT keeps the symbols
+	t.Setenv("SKIP_TRIMPATH", "1")
+	t.Setenv("DLV_PORT", "${DLV_PORT}")
+
+	var c Config
+	assert.NoError(t, targets.LoadFrom(t.TempDir(), Target, &c))
+	assert.False(t, c.Golang.SkipTrimpath)
+	assert.False(t, c.Golang.StripSymbols())
+}
+
+func TestExplain(t *testing.T) {
+	t.Setenv("GC_FLAGS", "all=-N -l")
+
+	var c Config
+	settings, err := targets.Explain(t.TempDir(), Target, &c)
+	assert.NoError(t, err)
+	assert.Equal(t, targets.Setting{Name: "build.golang.gcFlags", Value: "all=-N -l", Source: targets.SourceEnv, Origin: "GC_FLAGS"}, settings[0])
+	assert.Equal(t, targets.Setting{Name: "build.golang.debugPort", Value: "", Source: targets.SourceDefault}, settings[3])
+}